@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"machDB/src/internal/query"
+)
+
+// Formatos de salida del REPL, se cambian con \format.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatCSV   = "csv"
+)
+
+var formats = []string{FormatTable, FormatJSON, FormatYAML, FormatCSV}
+
+func validFormat(f string) bool {
+	for _, name := range formats {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// renderResult escribe res en w con el formato indicado. find, join y list son
+// tablas (columnas: id y la unión ordenada de campos); el resto de comandos es
+// un resumen (afectados, IDs, mensaje).
+func renderResult(w io.Writer, format string, res *query.Result) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(resultValue(res))
+	case FormatYAML:
+		v, err := plainValue(resultValue(res))
+		if err != nil {
+			return err
+		}
+		for _, line := range yamlLines(v) {
+			fmt.Fprintln(w, line)
+		}
+		return nil
+	case FormatCSV:
+		cols, rows, ok := tabular(res)
+		if !ok {
+			return renderSummary(w, res)
+		}
+		cw := csv.NewWriter(w)
+		cw.Write(cols)
+		cw.WriteAll(rows)
+		return cw.Error()
+	default:
+		cols, rows, ok := tabular(res)
+		if !ok {
+			return renderSummary(w, res)
+		}
+		writeTable(w, cols, rows)
+		return nil
+	}
+}
+
+// tabular convierte los resultados con filas en columnas y celdas.
+func tabular(res *query.Result) ([]string, [][]string, bool) {
+	switch {
+	case res.Command == "list":
+		rows := make([][]string, 0, len(res.Names))
+		for _, name := range res.Names {
+			rows = append(rows, []string{name})
+		}
+		return []string{"name"}, rows, true
+	case res.Rows != nil:
+		cols := fieldUnion(len(res.Rows), func(n int) map[string]interface{} { return res.Rows[n] })
+		rows := make([][]string, 0, len(res.Rows))
+		for _, r := range res.Rows {
+			rows = append(rows, cells(cols, r))
+		}
+		return cols, rows, true
+	case res.Objects != nil:
+		fields := fieldUnion(len(res.Objects), func(n int) map[string]interface{} { return res.Objects[n].Fields })
+		rows := make([][]string, 0, len(res.Objects))
+		for _, obj := range res.Objects {
+			rows = append(rows, append([]string{fmt.Sprint(obj.ID)}, cells(fields, obj.Fields)...))
+		}
+		return append([]string{"id"}, fields...), rows, true
+	}
+	return nil, nil, false
+}
+
+func fieldUnion(n int, fields func(int) map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var cols []string
+	for i := 0; i < n; i++ {
+		for k := range fields(i) {
+			if !seen[k] {
+				seen[k] = true
+				cols = append(cols, k)
+			}
+		}
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+func cells(cols []string, fields map[string]interface{}) []string {
+	out := make([]string, len(cols))
+	for n, k := range cols {
+		out[n] = cell(fields[k])
+	}
+	return out
+}
+
+// cell escribe escalares tal cual, ausentes y null vacíos y lo anidado como JSON.
+func cell(v interface{}) string {
+	switch tv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return tv
+	case bool, int, int64, float64:
+		return fmt.Sprint(tv)
+	default:
+		b, err := json.Marshal(tv)
+		if err != nil {
+			return fmt.Sprint(tv)
+		}
+		return string(b)
+	}
+}
+
+func writeTable(w io.Writer, cols []string, rows [][]string) {
+	widths := make([]int, len(cols))
+	for n, c := range cols {
+		widths[n] = utf8.RuneCountInString(c)
+	}
+	for _, r := range rows {
+		for n, c := range r {
+			if l := utf8.RuneCountInString(c); l > widths[n] {
+				widths[n] = l
+			}
+		}
+	}
+
+	line := func(values []string) {
+		var sb strings.Builder
+		for n, v := range values {
+			if n > 0 {
+				sb.WriteString(" | ")
+			}
+			sb.WriteString(v)
+			if n < len(values)-1 {
+				sb.WriteString(strings.Repeat(" ", widths[n]-utf8.RuneCountInString(v)))
+			}
+		}
+		fmt.Fprintln(w, sb.String())
+	}
+	line(cols)
+	sep := make([]string, len(cols))
+	for n := range cols {
+		sep[n] = strings.Repeat("-", widths[n])
+	}
+	fmt.Fprintln(w, strings.Join(sep, "-+-"))
+	for _, r := range rows {
+		line(r)
+	}
+	fmt.Fprintf(w, "(%d fila(s))\n", len(rows))
+}
+
+func renderSummary(w io.Writer, res *query.Result) error {
+	for _, e := range res.Errors {
+		fmt.Fprintln(w, "  error:", e)
+	}
+	switch {
+	case res.IDs != nil:
+		fmt.Fprintln(w, res.Message+":", res.IDs)
+	case res.Command == "modify":
+		fmt.Fprintf(w, "%d objeto(s) modificado(s)\n", res.Affected)
+	case res.Message != "":
+		fmt.Fprintln(w, res.Message)
+	default:
+		fmt.Fprintln(w, "OK")
+	}
+	return nil
+}
+
+// summary es el resumen de un comando sin filas en JSON/YAML.
+type summary struct {
+	Command  string   `json:"command"`
+	Affected int      `json:"affected"`
+	IDs      []int    `json:"ids,omitempty"`
+	Message  string   `json:"message,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// resultValue es lo que se serializa en JSON/YAML: la lista de nombres, objetos
+// ({id, fields}) o filas de join, o un summary.
+func resultValue(res *query.Result) interface{} {
+	switch {
+	case res.Command == "list":
+		if res.Names == nil {
+			return []string{}
+		}
+		return res.Names
+	case res.Rows != nil:
+		return res.Rows
+	case res.Objects != nil:
+		return res.Objects
+	}
+	return summary{Command: res.Command, Affected: res.Affected, IDs: res.IDs, Message: res.Message, Errors: res.Errors}
+}
+
+// plainValue pasa v por JSON para quedarse solo con mapas, listas y escalares.
+func plainValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out interface{}
+	err = dec.Decode(&out)
+	return out, err
+}
+
+// yamlLines emite v como YAML en bloque, con las claves ordenadas.
+func yamlLines(v interface{}) []string {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		if len(tv) == 0 {
+			return []string{"{}"}
+		}
+		keys := make([]string, 0, len(tv))
+		for k := range tv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var lines []string
+		for _, k := range keys {
+			child := yamlLines(tv[k])
+			if isYAMLBlock(tv[k]) {
+				lines = append(lines, yamlScalar(k)+":")
+				for _, l := range child {
+					lines = append(lines, "  "+l)
+				}
+				continue
+			}
+			lines = append(lines, yamlScalar(k)+": "+child[0])
+		}
+		return lines
+	case []interface{}:
+		if len(tv) == 0 {
+			return []string{"[]"}
+		}
+		var lines []string
+		for _, item := range tv {
+			child := yamlLines(item)
+			lines = append(lines, "- "+child[0])
+			for _, l := range child[1:] {
+				lines = append(lines, "  "+l)
+			}
+		}
+		return lines
+	default:
+		return []string{yamlScalar(tv)}
+	}
+}
+
+func isYAMLBlock(v interface{}) bool {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		return len(tv) > 0
+	case []interface{}:
+		return len(tv) > 0
+	}
+	return false
+}
+
+var (
+	yamlPlain    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_ ./-]*$`)
+	yamlReserved = map[string]bool{
+		"true": true, "false": true, "null": true, "yes": true, "no": true,
+		"on": true, "off": true, "y": true, "n": true, "~": true,
+	}
+)
+
+// yamlScalar deja sin comillas los strings que YAML no confunde con otra cosa;
+// el resto va entre comillas dobles (un string JSON es un string YAML válido).
+func yamlScalar(v interface{}) string {
+	switch tv := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return fmt.Sprint(tv)
+	case json.Number:
+		return tv.String()
+	case string:
+		if yamlPlain.MatchString(tv) && !strings.HasSuffix(tv, " ") && !yamlReserved[strings.ToLower(tv)] {
+			return tv
+		}
+		b, _ := json.Marshal(tv)
+		return string(b)
+	default:
+		return fmt.Sprint(tv)
+	}
+}
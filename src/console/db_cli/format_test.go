@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	db "machDB/src/internal/db"
+	"machDB/src/internal/query"
+)
+
+func TestRenderResult(t *testing.T) {
+	find := &query.Result{Command: "find", Objects: []*db.Object{
+		db.NewObject(0, map[string]interface{}{"name": "ana", "age": 30, "tags": []interface{}{"x", 2}}),
+		db.NewObject(12, map[string]interface{}{"name": "José", "vip": true, "note": nil}),
+	}}
+	join := &query.Result{Command: "find", Rows: []map[string]interface{}{
+		{"users.name": "ana", "orders.total": 2.5},
+	}}
+	list := &query.Result{Command: "list", Names: []string{"orders", "users"}}
+	insert := &query.Result{Command: "insert", IDs: []int{3, 4}, Affected: 2, Message: "Objetos insertados"}
+	imported := &query.Result{Command: "import", Affected: 1, Message: "1 objeto(s) importado(s)", Errors: []string{"fila 2: sin colección"}}
+
+	tests := []struct {
+		name   string
+		format string
+		res    *query.Result
+		want   string
+	}{
+		{"table find", FormatTable, find, "" +
+			"id | age | name | note | tags    | vip\n" +
+			"---+-----+------+------+---------+-----\n" +
+			"0  | 30  | ana  |      | [\"x\",2] | \n" +
+			"12 |     | José |      |         | true\n" +
+			"(2 fila(s))\n"},
+		{"table join", FormatTable, join, "" +
+			"orders.total | users.name\n" +
+			"-------------+-----------\n" +
+			"2.5          | ana\n" +
+			"(1 fila(s))\n"},
+		{"table empty find", FormatTable, &query.Result{Command: "find", Objects: []*db.Object{}}, "" +
+			"id\n" +
+			"--\n" +
+			"(0 fila(s))\n"},
+		{"table list", FormatTable, list, "name\n------\norders\nusers\n(2 fila(s))\n"},
+		{"table insert", FormatTable, insert, "Objetos insertados: [3 4]\n"},
+		{"table modify", FormatTable, &query.Result{Command: "modify", Affected: 3}, "3 objeto(s) modificado(s)\n"},
+		{"table import", FormatTable, imported, "  error: fila 2: sin colección\n1 objeto(s) importado(s)\n"},
+		{"table create", FormatTable, &query.Result{Command: "create", Affected: 1}, "OK\n"},
+		{"csv find", FormatCSV, find, "" +
+			"id,age,name,note,tags,vip\n" +
+			"0,30,ana,,\"[\"\"x\"\",2]\",\n" +
+			"12,,José,,,true\n"},
+		{"csv summary", FormatCSV, insert, "Objetos insertados: [3 4]\n"},
+		{"json find", FormatJSON, find, `[
+  {
+    "id": 0,
+    "fields": {
+      "age": 30,
+      "name": "ana",
+      "tags": [
+        "x",
+        2
+      ]
+    }
+  },
+  {
+    "id": 12,
+    "fields": {
+      "name": "José",
+      "note": null,
+      "vip": true
+    }
+  }
+]
+`},
+		{"json empty list", FormatJSON, &query.Result{Command: "list"}, "[]\n"},
+		{"json summary", FormatJSON, insert, `{
+  "command": "insert",
+  "affected": 2,
+  "ids": [
+    3,
+    4
+  ],
+  "message": "Objetos insertados"
+}
+`},
+		{"yaml find", FormatYAML, find, "" +
+			"- fields:\n" +
+			"    age: 30\n" +
+			"    name: ana\n" +
+			"    tags:\n" +
+			"      - x\n" +
+			"      - 2\n" +
+			"  id: 0\n" +
+			"- fields:\n" +
+			"    name: \"José\"\n" +
+			"    note: null\n" +
+			"    vip: true\n" +
+			"  id: 12\n"},
+		{"yaml join", FormatYAML, join, "- orders.total: 2.5\n  users.name: ana\n"},
+		{"yaml summary", FormatYAML, imported, "" +
+			"affected: 1\n" +
+			"command: import\n" +
+			"errors:\n" +
+			"  - \"fila 2: sin colección\"\n" +
+			"message: \"1 objeto(s) importado(s)\"\n"},
+		{"yaml empty", FormatYAML, &query.Result{Command: "find", Objects: []*db.Object{}}, "[]\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := renderResult(&buf, tc.format, tc.res); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tc.want {
+				t.Errorf("renderResult =\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+// Los strings que YAML leería como otro tipo van entre comillas.
+func TestYAMLScalar(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{"ana", "ana"},
+		{"Lima Centro", "Lima Centro"},
+		{"yes", `"yes"`},
+		{"Null", `"Null"`},
+		{"42", `"42"`},
+		{"a: b", `"a: b"`},
+		{"- x", `"- x"`},
+		{"trailing ", `"trailing "`},
+		{"", `""`},
+		{nil, "null"},
+		{false, "false"},
+	}
+	for _, tc := range tests {
+		if got := yamlScalar(tc.v); got != tc.want {
+			t.Errorf("yamlScalar(%#v) = %s, want %s", tc.v, got, tc.want)
+		}
+	}
+}
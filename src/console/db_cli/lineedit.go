@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxHistory es cuántas líneas del historial se conservan.
+const maxHistory = 1000
+
+// errInterrupted es Ctrl-C: se descarta la línea y se vuelve al prompt.
+var errInterrupted = errors.New("interrupted")
+
+// lineEditor lee líneas con edición estilo readline cuando stdin es una
+// terminal: flechas, Home/End, Ctrl-A/E/U/K/W, historial con ↑/↓ y Tab para
+// completar. Si no es una terminal (tubería, archivo) lee líneas tal cual.
+type lineEditor struct {
+	in       *os.File
+	out      io.Writer
+	reader   *bufio.Reader
+	tty      bool
+	history  []string
+	histPath string
+	complete func(line string) []string
+}
+
+func newLineEditor(histPath string, complete func(string) []string) *lineEditor {
+	ed := &lineEditor{
+		in:       os.Stdin,
+		out:      os.Stdout,
+		reader:   bufio.NewReader(os.Stdin),
+		tty:      isTerminal(int(os.Stdin.Fd())),
+		histPath: histPath,
+		complete: complete,
+	}
+	ed.loadHistory()
+	return ed
+}
+
+// historyPath devuelve $MACHDB_HISTORY o ~/.machdb_history ("" si no hay home).
+func historyPath() string {
+	if p := os.Getenv("MACHDB_HISTORY"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".machdb_history")
+}
+
+func (ed *lineEditor) loadHistory() {
+	if ed.histPath == "" {
+		return
+	}
+	data, err := os.ReadFile(ed.histPath)
+	if err != nil {
+		return
+	}
+	for _, l := range strings.Split(string(data), "\n") {
+		if l != "" {
+			ed.history = append(ed.history, l)
+		}
+	}
+	if len(ed.history) > maxHistory {
+		ed.history = ed.history[len(ed.history)-maxHistory:]
+		ed.rewriteHistory()
+	}
+}
+
+func (ed *lineEditor) rewriteHistory() {
+	data := strings.Join(ed.history, "\n") + "\n"
+	os.WriteFile(ed.histPath, []byte(data), 0o600)
+}
+
+// addHistory guarda line en memoria y al final del archivo (sin repetir la última).
+func (ed *lineEditor) addHistory(line string) {
+	if line == "" || strings.Contains(line, "\n") {
+		return
+	}
+	if n := len(ed.history); n > 0 && ed.history[n-1] == line {
+		return
+	}
+	ed.history = append(ed.history, line)
+	if ed.histPath == "" {
+		return
+	}
+	if len(ed.history) > maxHistory {
+		ed.history = ed.history[len(ed.history)-maxHistory:]
+		ed.rewriteHistory()
+		return
+	}
+	f, err := os.OpenFile(ed.histPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(f, line)
+	f.Close()
+}
+
+// readLine muestra prompt y devuelve la línea sin el salto final. Devuelve
+// io.EOF con Ctrl-D en una línea vacía (o al final de la entrada) y
+// errInterrupted con Ctrl-C.
+func (ed *lineEditor) readLine(prompt string) (string, error) {
+	if !ed.tty {
+		fmt.Fprint(ed.out, prompt)
+		line, err := ed.reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fd := int(ed.in.Fd())
+	st, err := makeRaw(fd)
+	if err != nil {
+		ed.tty = false
+		return ed.readLine(prompt)
+	}
+	defer restoreTerm(fd, st)
+
+	line, err := ed.edit(prompt)
+	if err == nil {
+		ed.addHistory(strings.TrimSpace(line))
+	}
+	return line, err
+}
+
+// edit es el bucle de edición en modo raw.
+func (ed *lineEditor) edit(prompt string) (string, error) {
+	var buf []rune
+	pos := 0
+	histIdx := len(ed.history)
+	var pending []rune // línea en edición mientras se navega el historial
+
+	refresh := func() {
+		fmt.Fprintf(ed.out, "\r%s%s\x1b[K\r", prompt, string(buf))
+		if n := len([]rune(prompt)) + pos; n > 0 {
+			fmt.Fprintf(ed.out, "\x1b[%dC", n)
+		}
+	}
+	setLine := func(s []rune) {
+		buf = append(buf[:0:0], s...)
+		pos = len(buf)
+		refresh()
+	}
+	insert := func(rs ...rune) {
+		buf = append(buf[:pos], append(rs, buf[pos:]...)...)
+		pos += len(rs)
+		refresh()
+	}
+
+	refresh()
+	for {
+		r, _, err := ed.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(ed.out, "\r\n")
+			return string(buf), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(ed.out, "^C\r\n")
+			return "", errInterrupted
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				fmt.Fprint(ed.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+				refresh()
+			}
+		case 127, 8: // Backspace
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+				refresh()
+			}
+		case 1: // Ctrl-A
+			pos = 0
+			refresh()
+		case 5: // Ctrl-E
+			pos = len(buf)
+			refresh()
+		case 2: // Ctrl-B
+			if pos > 0 {
+				pos--
+				refresh()
+			}
+		case 6: // Ctrl-F
+			if pos < len(buf) {
+				pos++
+				refresh()
+			}
+		case 11: // Ctrl-K
+			buf = buf[:pos]
+			refresh()
+		case 21: // Ctrl-U
+			buf = append(buf[:0:0], buf[pos:]...)
+			pos = 0
+			refresh()
+		case 23: // Ctrl-W
+			start := pos
+			for start > 0 && buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && buf[start-1] != ' ' {
+				start--
+			}
+			buf = append(buf[:start], buf[pos:]...)
+			pos = start
+			refresh()
+		case 12: // Ctrl-L
+			fmt.Fprint(ed.out, "\x1b[H\x1b[2J")
+			refresh()
+		case '\t':
+			ed.completeAt(prompt, &buf, &pos, refresh)
+		case 27: // secuencias de escape
+			key := ed.readEscape()
+			switch key {
+			case "up", "down":
+				if key == "up" && histIdx > 0 {
+					if histIdx == len(ed.history) {
+						pending = append(pending[:0:0], buf...)
+					}
+					histIdx--
+					setLine([]rune(ed.history[histIdx]))
+				} else if key == "down" && histIdx < len(ed.history) {
+					histIdx++
+					if histIdx == len(ed.history) {
+						setLine(pending)
+					} else {
+						setLine([]rune(ed.history[histIdx]))
+					}
+				}
+			case "left":
+				if pos > 0 {
+					pos--
+					refresh()
+				}
+			case "right":
+				if pos < len(buf) {
+					pos++
+					refresh()
+				}
+			case "home":
+				pos = 0
+				refresh()
+			case "end":
+				pos = len(buf)
+				refresh()
+			case "delete":
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+					refresh()
+				}
+			}
+		default:
+			if r >= 32 {
+				insert(r)
+			}
+		}
+	}
+}
+
+// readEscape interpreta lo que sigue a ESC: ESC [ A..D, ESC [ H/F, ESC O H/F y
+// ESC [ n ~ (1/7 Home, 4/8 End, 3 Supr).
+func (ed *lineEditor) readEscape() string {
+	b, err := ed.reader.ReadByte()
+	if err != nil || (b != '[' && b != 'O') {
+		return ""
+	}
+	c, err := ed.reader.ReadByte()
+	if err != nil {
+		return ""
+	}
+	switch c {
+	case 'A':
+		return "up"
+	case 'B':
+		return "down"
+	case 'C':
+		return "right"
+	case 'D':
+		return "left"
+	case 'H':
+		return "home"
+	case 'F':
+		return "end"
+	}
+	if c < '0' || c > '9' {
+		return ""
+	}
+	seq := []byte{c}
+	for {
+		d, err := ed.reader.ReadByte()
+		if err != nil {
+			return ""
+		}
+		if d == '~' {
+			break
+		}
+		seq = append(seq, d)
+	}
+	switch string(seq) {
+	case "1", "7":
+		return "home"
+	case "4", "8":
+		return "end"
+	case "3":
+		return "delete"
+	}
+	return ""
+}
+
+// completeAt completa la palabra bajo el cursor: con un candidato la escribe
+// entera, con varios avanza hasta el prefijo común y, si no hay nada que
+// añadir, los lista debajo del prompt.
+func (ed *lineEditor) completeAt(prompt string, buf *[]rune, pos *int, refresh func()) {
+	if ed.complete == nil {
+		return
+	}
+	before := string((*buf)[:*pos])
+	start := strings.LastIndexAny(before, " \t") + 1
+	word := before[start:]
+	cands := ed.complete(before)
+	if len(cands) == 0 {
+		return
+	}
+
+	prefix := cands[0]
+	for _, c := range cands[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	add := []rune(strings.TrimPrefix(prefix, word))
+	if len(cands) == 1 {
+		add = append(add, ' ')
+	}
+	if len(add) > 0 && strings.HasPrefix(prefix, word) {
+		*buf = append((*buf)[:*pos], append(add, (*buf)[*pos:]...)...)
+		*pos += len(add)
+		refresh()
+		return
+	}
+	fmt.Fprintf(ed.out, "\r\n%s\r\n", strings.Join(cands, "  "))
+	refresh()
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Teclas como las manda una terminal en modo raw.
+const (
+	keyUp    = "\x1b[A"
+	keyDown  = "\x1b[B"
+	keyRight = "\x1b[C"
+	keyLeft  = "\x1b[D"
+	keyHome  = "\x1b[H"
+	keyEnd   = "\x1bOF"
+	keyDel   = "\x1b[3~"
+	keyHome1 = "\x1b[1~"
+	keyEnd4  = "\x1b[4~"
+)
+
+// testEditor es un lineEditor que lee input y escribe en out, sin archivo de
+// historial salvo que se le ponga uno.
+func testEditor(input string, history ...string) (*lineEditor, *strings.Builder) {
+	out := &strings.Builder{}
+	return &lineEditor{
+		out:     out,
+		reader:  bufio.NewReader(strings.NewReader(input)),
+		history: history,
+	}, out
+}
+
+func TestEditKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain", "list db\r", "list db"},
+		{"newline", "list db\n", "list db"},
+		{"backspace", "lisx\x7ft db\r", "list db"},
+		{"ctrl-h", "lisx\bt db\r", "list db"},
+		{"backspace at start", "\x7fab\r", "ab"},
+		{"left and insert", "lst" + keyLeft + keyLeft + "i\r", "list"},
+		{"right past the end", "ab" + keyLeft + keyRight + keyRight + "c\r", "abc"},
+		{"home and end", "b" + keyHome + "a" + keyEnd + "c\r", "abc"},
+		{"home and end with ~", "b" + keyHome1 + "a" + keyEnd4 + "c\r", "abc"},
+		{"ctrl-a and ctrl-e", "b\x01a\x05c\r", "abc"},
+		{"ctrl-b and ctrl-f", "ac\x02b\x06d\r", "abcd"},
+		{"delete under the cursor", "abxc" + keyLeft + keyLeft + keyDel + "\r", "abc"},
+		{"delete at the end", "abc" + keyDel + "\r", "abc"},
+		{"ctrl-d deletes under the cursor", "abxc\x02\x02\x04\r", "abc"},
+		{"ctrl-k", "list db" + keyLeft + keyLeft + keyLeft + "\x0b\r", "list"},
+		{"ctrl-u", "xxx list" + keyLeft + keyLeft + keyLeft + keyLeft + "\x15\r", "list"},
+		{"ctrl-w", "find where  age\x17name\r", "find where  name"},
+		{"ctrl-w with trailing spaces", "find where  \x17\r", "find "},
+		{"ctrl-w at start", "\x17ab\r", "ab"},
+		{"unknown escape", "a\x1b[Zb\x1bxc\r", "abc"},
+		{"control chars ignored", "a\x00\x10b\r", "ab"},
+		{"utf-8", "José" + keyLeft + keyLeft + "\x7f\r", "Jsé"},
+		{"ctrl-l keeps the line", "ab\x0cc\r", "abc"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ed, _ := testEditor(tc.input)
+			got, err := ed.edit("> ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("edit = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEditInterruptAndEOF(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"ctrl-c", "list\x03", errInterrupted},
+		{"ctrl-d on an empty line", "\x04", io.EOF},
+		{"ctrl-d after deleting everything", "ab\x15\x04", io.EOF},
+		{"end of input", "list", io.EOF},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ed, _ := testEditor(tc.input)
+			if line, err := ed.edit("> "); err != tc.wantErr || line != "" {
+				t.Errorf("edit = %q, %v; want %v", line, err, tc.wantErr)
+			}
+		})
+	}
+
+	// la línea siguiente a un Ctrl-C empieza vacía
+	ed, _ := testEditor("abc\x03def\r")
+	ed.edit("> ")
+	if line, err := ed.edit("> "); err != nil || line != "def" {
+		t.Errorf("edit after ctrl-c = %q, %v; want def", line, err)
+	}
+}
+
+func TestEditHistory(t *testing.T) {
+	history := []string{"list db", "select db shop", "list collections"}
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"up", keyUp + "\r", "list collections"},
+		{"up twice", keyUp + keyUp + "\r", "select db shop"},
+		{"up past the oldest", strings.Repeat(keyUp, 5) + "\r", "list db"},
+		{"up and down", keyUp + keyUp + keyDown + "\r", "list collections"},
+		{"down restores the line being typed", "fin" + keyUp + keyUp + keyDown + keyDown + "d\r", "find"},
+		{"down on the new line", "x" + keyDown + "\r", "x"},
+		{"edit a history entry", keyUp + "\x17users\r", "list users"},
+		{"edited entry is not saved", keyUp + "\x7f" + keyDown + keyUp + "\r", "list collections"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ed, _ := testEditor(tc.input, history...)
+			got, err := ed.edit("> ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("edit = %q, want %q", got, tc.want)
+			}
+			if !reflect.DeepEqual(ed.history, history) {
+				t.Errorf("history changed to %q", ed.history)
+			}
+		})
+	}
+}
+
+func TestEditComplete(t *testing.T) {
+	complete := func(line string) []string {
+		words := map[string][]string{
+			"":           {"create", "delete", "find", "list"},
+			"l":          {"list"},
+			"s":          {"select", "set"},
+			"se":         {"select", "set"},
+			"find wh":    {"where"},
+			"list col":   {"collections"},
+			"create doc": {"documents"},
+		}
+		return words[line]
+	}
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		listed string // candidatos escritos debajo del prompt
+	}{
+		{"one candidate", "l\t\r", "list ", ""},
+		{"after a word", "find wh\t\r", "find where ", ""},
+		{"common prefix", "s\t\r", "se", ""},
+		{"nothing to add lists them", "se\t\r", "se", "select  set"},
+		{"empty line lists everything", "\t\r", "", "create  delete  find  list"},
+		{"no candidates", "x\t\r", "x", ""},
+		{"in the middle of the line", "list colX" + keyLeft + "\t\r", "list collections X", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ed, out := testEditor(tc.input)
+			ed.complete = complete
+			got, err := ed.edit("> ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("edit = %q, want %q", got, tc.want)
+			}
+			listed := strings.Contains(out.String(), "\r\n"+tc.listed+"\r\n")
+			if tc.listed != "" && !listed {
+				t.Errorf("candidates %q not listed in %q", tc.listed, out.String())
+			}
+		})
+	}
+}
+
+// Sin terminal readLine lee líneas tal cual y no toca el historial.
+func TestReadLinePipe(t *testing.T) {
+	ed, out := testEditor("list db\r\n\nfind \x1b[A\nlast")
+	var lines []string
+	for {
+		line, err := ed.readLine("> ")
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if want := []string{"list db", "", "find \x1b[A", "last"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+	if got := out.String(); got != strings.Repeat("> ", 5) {
+		t.Errorf("output = %q, want only the prompts", got)
+	}
+	if len(ed.history) != 0 {
+		t.Errorf("history = %q, want empty", ed.history)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	ed, _ := testEditor("")
+	ed.histPath = path
+	for _, line := range []string{"list db", "list db", "", "a\nb", "select db shop", "list db"} {
+		ed.addHistory(line)
+	}
+	want := []string{"list db", "select db shop", "list db"}
+	if !reflect.DeepEqual(ed.history, want) {
+		t.Errorf("history = %q, want %q", ed.history, want)
+	}
+
+	loaded, _ := testEditor("")
+	loaded.histPath = path
+	loaded.loadHistory()
+	if !reflect.DeepEqual(loaded.history, want) {
+		t.Errorf("loaded history = %q, want %q", loaded.history, want)
+	}
+}
+
+func TestHistoryFileLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	var sb strings.Builder
+	for n := 0; n < maxHistory+5; n++ {
+		fmt.Fprintf(&sb, "cmd %d\n", n)
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ed, _ := testEditor("")
+	ed.histPath = path
+	ed.loadHistory()
+	if len(ed.history) != maxHistory || ed.history[0] != "cmd 5" {
+		t.Fatalf("loaded %d lines starting at %q, want %d starting at cmd 5", len(ed.history), ed.history[0], maxHistory)
+	}
+	ed.addHistory("new")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != maxHistory || lines[0] != "cmd 6" || lines[len(lines)-1] != "new" {
+		t.Errorf("file has %d lines from %q to %q, want %d from cmd 6 to new", len(lines), lines[0], lines[len(lines)-1], maxHistory)
+	}
+}
@@ -1,7 +1,8 @@
 package main
 
 import (
-	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
@@ -9,43 +10,178 @@ import (
 	"machDB/src/internal/query"
 )
 
+// session es el estado del CLI que no pertenece al intérprete.
+type session struct {
+	inter  *query.Interpreter
+	format string
+}
+
 func main() {
-	fmt.Println("Interpreter DB CLI")
-	inter, err := query.NewInterpreter("/db")
+	dbPath := flag.String("path", "/db", "directorio base de las bases de datos o archivo .machdb")
+	format := flag.String("format", FormatTable, "formato de salida: "+strings.Join(formats, ", "))
+	script := flag.String("f", "", "ejecuta los comandos del archivo (- para stdin) y termina")
+	inline := flag.String("e", "", "ejecuta los comandos separados por ';' y termina")
+	keepGoing := flag.Bool("continue", false, "con -f/-e, sigue tras un error (el código de salida sigue siendo 1)")
+	flag.Parse()
+	if !validFormat(*format) {
+		fmt.Fprintln(os.Stderr, "Unknown format:", *format)
+		os.Exit(2)
+	}
+	if *script != "" && *inline != "" {
+		fmt.Fprintln(os.Stderr, "-f and -e are mutually exclusive")
+		os.Exit(2)
+	}
+
+	interactive := *script == "" && *inline == ""
+	if interactive {
+		fmt.Println("Interpreter DB CLI")
+	}
+	inter, err := query.NewInterpreter(*dbPath)
 	if err != nil {
-		fmt.Println("Error initializing interpreter:", err)
+		fmt.Fprintln(os.Stderr, "Error initializing interpreter:", err)
+		os.Exit(1)
+	}
+	s := &session{inter: inter, format: *format}
+
+	if interactive {
+		s.repl()
 		return
 	}
-	scanner := bufio.NewScanner(os.Stdin)
 
+	var stmts []statement
+	if *inline != "" {
+		stmts, err = splitStatements("-e", *inline)
+	} else {
+		stmts, err = readScript(*script)
+	}
+	failed := err != nil
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		failed = s.runScript(stmts, *keepGoing)
+	}
+	if inter.InTx() {
+		fmt.Fprintln(os.Stderr, "Transaction not committed, changes discarded")
+		failed = true
+	}
+
+	if err := inter.Save(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error saving to disk:", err)
+		failed = true
+	}
+	inter.Close()
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func (s *session) repl() {
+	ed := newLineEditor(historyPath(), s.complete)
 	for {
-		fmt.Print("> ")
-		if !scanner.Scan() {
+		prompt := "> "
+		if s.inter.InTx() {
+			prompt = "*> "
+		}
+		line, err := ed.readLine(prompt)
+		if err == errInterrupted {
+			continue
+		}
+		if err != nil {
 			break
 		}
-		line := scanner.Text()
-		if strings.TrimSpace(line) == "" {
+		line = strings.TrimSpace(line)
+		if line == "" {
 			continue
 		}
 		if strings.ToLower(line) == "exit" {
-			inter.Save()
+			if s.inter.InTx() {
+				fmt.Println("Transaction not committed, changes discarded")
+			}
 			fmt.Println("Saving changes to disk...")
+			if err := s.inter.Save(); err != nil {
+				fmt.Println("Error saving to disk:", err)
+			}
+			s.inter.Close()
 			fmt.Println("bye, see you later.")
 			break
 		}
+		if err := s.run(line); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
 
-		lex := query.NewLexer(line)
-		parser := query.NewParser(lex)
-
-		cmd, err := parser.ParseCommand()
-		if err != nil {
-			fmt.Println("Parse error:", err)
-			continue
+// complete añade a Interpreter.Complete los meta comandos y exit del REPL.
+func (s *session) complete(line string) []string {
+	trimmed := strings.TrimLeft(line, " ")
+	if strings.HasPrefix(trimmed, `\`) {
+		words := strings.Fields(trimmed)
+		var cands []string
+		prefix := ""
+		if !strings.HasSuffix(trimmed, " ") {
+			prefix = words[len(words)-1]
+			words = words[:len(words)-1]
+		}
+		switch {
+		case len(words) == 0:
+			cands = []string{`\format`}
+		case len(words) == 1 && words[0] == `\format`:
+			cands = formats
 		}
+		var out []string
+		for _, c := range cands {
+			if strings.HasPrefix(c, prefix) {
+				out = append(out, c)
+			}
+		}
+		return out
+	}
 
-		err = inter.Execute(cmd)
-		if err != nil {
-			fmt.Println("Error ejecutando comando:", err)
+	cands := s.inter.Complete(line)
+	if !strings.Contains(trimmed, " ") && strings.HasPrefix("exit", trimmed) {
+		cands = append(cands, "exit")
+	}
+	return cands
+}
+
+// run ejecuta una sentencia (comando o meta comando) y muestra su resultado.
+func (s *session) run(line string) error {
+	if strings.HasPrefix(line, `\`) {
+		return s.metaCommand(strings.Fields(line))
+	}
+
+	cmd, err := query.NewParser(query.NewLexer(line)).ParseCommand()
+	if err != nil {
+		return fmt.Errorf("Parse error: %w", err)
+	}
+	res, err := s.inter.Execute(cmd)
+	if err != nil {
+		return fmt.Errorf("Error ejecutando comando: %w", err)
+	}
+	if err := renderResult(os.Stdout, s.format, res); err != nil {
+		return fmt.Errorf("Error mostrando resultado: %w", err)
+	}
+	return nil
+}
+
+// metaCommand atiende las líneas que empiezan con \ (opciones del REPL, no del
+// lenguaje de consultas):
+//
+//	\format                    muestra el formato actual
+//	\format table|json|yaml|csv cambia el formato de salida
+func (s *session) metaCommand(args []string) error {
+	switch args[0] {
+	case `\format`:
+		if len(args) == 1 {
+			fmt.Println("format:", s.format)
+			return nil
+		}
+		if !validFormat(args[1]) {
+			return fmt.Errorf("Unknown format %s (use %s)", args[1], strings.Join(formats, ", "))
 		}
+		s.format = args[1]
+		return nil
+	default:
+		return errors.New("Unknown meta command: " + args[0])
 	}
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// statement es una sentencia de un script con su origen, para los mensajes de error.
+type statement struct {
+	source string
+	line   int
+	text   string
+}
+
+// readScript lee path (o stdin si es "-") y lo parte en sentencias.
+func readScript(path string) ([]statement, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return splitStatements(path, string(data))
+}
+
+// splitStatements parte src en sentencias separadas por ';' o fin de línea.
+// Los ';' y saltos de línea entre comillas no cortan, y las líneas que empiezan
+// con '#' o '--' son comentarios hasta el fin de línea, con ';' o sin ellos.
+func splitStatements(source, src string) ([]statement, error) {
+	var stmts []statement
+	var cur strings.Builder
+	line, start := 1, 1
+	var quote rune
+	comment := false
+
+	flush := func() {
+		text := strings.TrimSpace(cur.String())
+		if text != "" {
+			stmts = append(stmts, statement{source: source, line: start, text: text})
+		}
+		cur.Reset()
+	}
+
+	for i, r := range src {
+		switch {
+		case comment:
+			if r == '\n' {
+				comment = false
+				line++
+				start = line
+			}
+			continue
+		case cur.Len() == 0 && (r == '#' || strings.HasPrefix(src[i:], "--")):
+			comment = true
+			continue
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ';':
+			flush()
+			start = line
+			continue
+		case r == '\n':
+			flush()
+			line++
+			start = line
+			continue
+		}
+		if r == '\n' {
+			line++
+		}
+		if cur.Len() == 0 && (r == ' ' || r == '\t' || r == '\r') {
+			continue
+		}
+		if cur.Len() == 0 {
+			start = line
+		}
+		cur.WriteRune(r)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("%s:%d: unterminated string", source, start)
+	}
+	flush()
+	return stmts, nil
+}
+
+// runScript ejecuta stmts en orden. Se detiene en el primer error salvo con
+// keepGoing; devuelve true si alguna sentencia falló. "exit" termina el script.
+func (s *session) runScript(stmts []statement, keepGoing bool) bool {
+	failed := false
+	for _, st := range stmts {
+		if strings.ToLower(st.text) == "exit" {
+			break
+		}
+		if err := s.run(st.text); err != nil {
+			fmt.Fprintf(os.Stderr, "%s:%d: %v\n", st.source, st.line, err)
+			failed = true
+			if !keepGoing {
+				break
+			}
+		}
+	}
+	return failed
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Con DB_CLI_MAIN=1 el binario de test se comporta como db_cli, para probar
+// los flags y el código de salida de main.
+func TestMain(m *testing.M) {
+	if os.Getenv("DB_CLI_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// cli ejecuta db_cli con args y stdin y devuelve stdout, stderr y el código de salida.
+func cli(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "DB_CLI_MAIN=1")
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return stdout.String(), stderr.String(), 0
+	case errors.As(err, &exitErr):
+		return stdout.String(), stderr.String(), exitErr.ExitCode()
+	}
+	t.Fatal(err)
+	return "", "", 0
+}
+
+const setup = "create db shop; select db shop; create collections users; select collection users; create documents d"
+
+// names lista con -e las filas id,name de los objetos de shop/users/d.
+func names(t *testing.T, dir string) string {
+	t.Helper()
+	stdout, stderr, code := cli(t, "", "-path", dir, "-format", "csv",
+		"-e", `select db shop; select collection users; find where name != ""`)
+	if code != 0 {
+		t.Fatalf("listing names: exit %d: %s", code, stderr)
+	}
+	// los select escriben su mensaje antes de la tabla id,name
+	_, table, _ := strings.Cut(stdout, "id,name\n")
+	return strings.Join(strings.Fields(table), " ")
+}
+
+func TestScriptInline(t *testing.T) {
+	dir := t.TempDir()
+	stdout, stderr, code := cli(t, "", "-path", dir, "-e", setup+`; insert [{name:"a;b"},{name:"c"}] in document d`)
+	if code != 0 || stderr != "" {
+		t.Fatalf("exit %d, stderr %q", code, stderr)
+	}
+	if strings.Contains(stdout, "Interpreter DB CLI") {
+		t.Errorf("-e printed the REPL banner: %q", stdout)
+	}
+	if got := names(t, dir); got != "0,a;b 1,c" {
+		t.Errorf("names after -e = %q, want %q", got, "0,a;b 1,c")
+	}
+}
+
+func TestScriptFile(t *testing.T) {
+	script := `# datos de prueba
+create db shop
+select db shop; create collections users
+select collection users
+-- un documento
+create documents d
+insert [{name:"a"}] in document d
+insert [{name:"b"}] in document nope
+insert [{name:"c"}] in document d
+`
+	tests := []struct {
+		name      string
+		args      []string
+		stdin     string
+		wantNames string
+	}{
+		{"stops at the first error", []string{"-f", "script.mdb"}, "", "0,a"},
+		{"continue after an error", []string{"-f", "script.mdb", "-continue"}, "", "0,a 1,c"},
+		{"from stdin", []string{"-f", "-"}, script, "0,a"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "script.mdb")
+			if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			args := append([]string{"-path", filepath.Join(dir, "data")}, tc.args...)
+			for n, a := range args {
+				if a == "script.mdb" {
+					args[n] = path
+				}
+			}
+			_, stderr, code := cli(t, tc.stdin, args...)
+			if code != 1 {
+				t.Errorf("exit %d, want 1", code)
+			}
+			source := path
+			if tc.stdin != "" {
+				source = "-"
+			}
+			if want := source + ":8: "; !strings.HasPrefix(stderr, want) || strings.Count(stderr, "\n") != 1 {
+				t.Errorf("stderr = %q, want one error starting with %q", stderr, want)
+			}
+			if got := names(t, filepath.Join(dir, "data")); got != tc.wantNames {
+				t.Errorf("names = %q, want %q", got, tc.wantNames)
+			}
+		})
+	}
+}
+
+func TestScriptExit(t *testing.T) {
+	dir := t.TempDir()
+	_, stderr, code := cli(t, "", "-path", dir, "-e", setup+`; insert [{name:"a"}] in document d; exit; insert [{name:"b"}] in document d`)
+	if code != 0 || stderr != "" {
+		t.Fatalf("exit %d, stderr %q", code, stderr)
+	}
+	if got := names(t, dir); got != "0,a" {
+		t.Errorf("names = %q, want only the statements before exit", got)
+	}
+}
+
+// Una transacción abierta al terminar el script se descarta y es un error.
+func TestScriptOpenTransaction(t *testing.T) {
+	dir := t.TempDir()
+	if _, stderr, code := cli(t, "", "-path", dir, "-e", setup+`; insert [{name:"a"}] in document d`); code != 0 {
+		t.Fatalf("setup: exit %d: %s", code, stderr)
+	}
+	_, stderr, code := cli(t, "", "-path", dir, "-e", `select db shop; select collection users; begin; insert [{name:"b"}] in document d`)
+	if code != 1 || !strings.Contains(stderr, "Transaction not committed") {
+		t.Errorf("exit %d, stderr %q; want 1 and the discarded transaction", code, stderr)
+	}
+	if got := names(t, dir); got != "0,a" {
+		t.Errorf("names = %q, want 0,a", got)
+	}
+}
+
+func TestScriptBadFlags(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"-e", "list db", "-f", "x.mdb"}, 2},
+		{[]string{"-format", "xml", "-e", "list db"}, 2},
+		{[]string{"-f", filepath.Join(dir, "missing.mdb")}, 1},
+		{[]string{"-e", `create db "shop`}, 1},
+	}
+	for _, tc := range tests {
+		_, stderr, code := cli(t, "", append([]string{"-path", dir}, tc.args...)...)
+		if code != tc.code || stderr == "" {
+			t.Errorf("%v: exit %d, stderr %q; want %d and a message", tc.args, code, stderr, tc.code)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	src := "create db shop; select db shop\n" +
+		"# comentario; no se parte\n" +
+		"  insert [{a:\"x;y\"},{b:'it''s\n" +
+		"two'}] in document d ;;\n" +
+		"-- otro\n" +
+		"\texit\n"
+	stmts, err := splitStatements("s", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []statement{
+		{"s", 1, "create db shop"},
+		{"s", 1, "select db shop"},
+		{"s", 3, "insert [{a:\"x;y\"},{b:'it''s\ntwo'}] in document d"},
+		{"s", 6, "exit"},
+	}
+	if !reflect.DeepEqual(stmts, want) {
+		t.Errorf("splitStatements =\n%+v\nwant\n%+v", stmts, want)
+	}
+
+	if _, err := splitStatements("s", "list db\ninsert [{a:\"x}] in document d"); err == nil || !strings.HasPrefix(err.Error(), "s:2:") {
+		t.Errorf("unterminated string: err = %v, want s:2: ...", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// Fuera de Linux no hay modo raw: el REPL lee líneas sin edición.
+type termState struct{}
+
+func isTerminal(fd int) bool { return false }
+
+func makeRaw(fd int) (*termState, error) {
+	return nil, errors.New("raw terminal mode not supported on this platform")
+}
+
+func restoreTerm(fd int, st *termState) error { return nil }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"machDB/src/internal/engine"
+	"machDB/src/internal/server"
+	"machDB/src/internal/storage"
+)
+
+func main() {
+	dbPath := flag.String("path", "/db", "directorio base de las bases de datos o archivo .machdb")
+	addr := flag.String("addr", ":7070", "dirección en la que escucha el servidor HTTP")
+	flag.Parse()
+
+	eng := engine.NewEngine()
+	store := storage.NewStorage(*dbPath)
+	if err := store.LoadFromDisk(eng); err != nil {
+		fmt.Println("Error loading from disk:", err)
+		os.Exit(1)
+	}
+
+	srv := &http.Server{Addr: *addr, Handler: server.New(eng)}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	fmt.Println("machDB server listening on", *addr)
+	err := srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Println("Server error:", err)
+	}
+
+	fmt.Println("Saving changes to disk...")
+	if err := store.FlushToDisk(eng); err != nil {
+		fmt.Println("Error saving to disk:", err)
+	}
+	store.Close(eng)
+	fmt.Println("bye, see you later.")
+}
@@ -1,11 +1,22 @@
 package core
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
-// Collection → representa una colección dentro de una base de datos
+// Collection → representa una colección dentro de una base de datos. El RWMutex
+// protege Documents y Keys (lo toma quien llama a los métodos)
 type Collection struct {
-	Name      string
-	Documents map[string]*Document `json:"documents"`
+	sync.RWMutex `json:"-"`
+	Name         string
+	Documents    map[string]*Document `json:"documents"`
+	Keys         string               `json:"keys,omitempty"` // KeysNone, KeysObjectID o KeysUUID
+}
+
+// CollectionMeta → lo que se guarda de una colección aparte de sus documentos
+type CollectionMeta struct {
+	Keys string `json:"keys,omitempty"`
 }
 
 // NewCollection → constructor
@@ -19,7 +30,7 @@ func NewCollection(name string) *Collection {
 // CreateDocument → crea un documento vacío con un ID
 func (c *Collection) CreateDocument(id string) error {
 	if _, exists := c.Documents[id]; exists {
-		return fmt.Errorf("document %s already exists", id)
+		return fmt.Errorf("document %s %w", id, ErrAlreadyExists)
 	}
 	c.Documents[id] = NewDocument(id)
 	return nil
@@ -29,7 +40,7 @@ func (c *Collection) CreateDocument(id string) error {
 func (c *Collection) GetDocument(id string) (*Document, error) {
 	doc, ok := c.Documents[id]
 	if !ok {
-		return nil, fmt.Errorf("document %s not found", id)
+		return nil, fmt.Errorf("document %s %w", id, ErrNotFound)
 	}
 	return doc, nil
 }
@@ -37,8 +48,39 @@ func (c *Collection) GetDocument(id string) (*Document, error) {
 // DeleteDocument → elimina un documento
 func (c *Collection) DeleteDocument(id string) error {
 	if _, ok := c.Documents[id]; !ok {
-		return fmt.Errorf("document %s not found", id)
+		return fmt.Errorf("document %s %w", id, ErrNotFound)
 	}
 	delete(c.Documents, id)
 	return nil
 }
+
+// AssignKeys → si la colección tiene Keys, da una clave nueva a cada objeto de
+// objs que no traiga KeyField. Sustituye los mapas en el propio slice (por
+// copias con la clave), así que el llamador ve las claves asignadas
+func (c *Collection) AssignKeys(objs []map[string]interface{}) {
+	if c.Keys == KeysNone {
+		return
+	}
+	for n, obj := range objs {
+		if _, ok := obj[KeyField]; ok {
+			continue
+		}
+		keyed := make(map[string]interface{}, len(obj)+1)
+		for k, v := range obj {
+			keyed[k] = v
+		}
+		keyed[KeyField] = NewKey(c.Keys)
+		objs[n] = keyed
+	}
+}
+
+// Copy → copia superficial: un mapa de documentos propio que apunta a los
+// mismos documentos (quien llama tiene c tomada)
+func (c *Collection) Copy() *Collection {
+	out := NewCollection(c.Name)
+	out.Keys = c.Keys
+	for id, doc := range c.Documents {
+		out.Documents[id] = doc
+	}
+	return out
+}
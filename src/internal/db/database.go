@@ -1,11 +1,16 @@
 package core
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
-// Database → representa una base de datos en memoria
+// Database → representa una base de datos en memoria. El RWMutex protege
+// Collections (lo toma quien llama a los métodos)
 type Database struct {
-	Name        string
-	Collections map[string]*Collection `json:"collections"`
+	sync.RWMutex `json:"-"`
+	Name         string
+	Collections  map[string]*Collection `json:"collections"`
 }
 
 // NewDatabase → constructor
@@ -19,7 +24,7 @@ func NewDatabase(name string) *Database {
 // CreateCollection → crea una colección
 func (db *Database) CreateCollection(name string) error {
 	if _, exists := db.Collections[name]; exists {
-		return fmt.Errorf("collection %s already exists", name)
+		return fmt.Errorf("collection %s %w", name, ErrAlreadyExists)
 	}
 	db.Collections[name] = NewCollection(name)
 	return nil
@@ -29,7 +34,7 @@ func (db *Database) CreateCollection(name string) error {
 func (db *Database) GetCollection(name string) (*Collection, error) {
 	col, ok := db.Collections[name]
 	if !ok {
-		return nil, fmt.Errorf("collection %s not found", name)
+		return nil, fmt.Errorf("collection %s %w", name, ErrNotFound)
 	}
 	return col, nil
 }
@@ -37,8 +42,18 @@ func (db *Database) GetCollection(name string) (*Collection, error) {
 // DeleteCollection → elimina una colección
 func (db *Database) DeleteCollection(name string) error {
 	if _, ok := db.Collections[name]; !ok {
-		return fmt.Errorf("collection %s not found", name)
+		return fmt.Errorf("collection %s %w", name, ErrNotFound)
 	}
 	delete(db.Collections, name)
 	return nil
 }
+
+// Copy → copia superficial: un mapa de colecciones propio que apunta a las
+// mismas colecciones (quien llama tiene db tomada)
+func (db *Database) Copy() *Database {
+	out := NewDatabase(db.Name)
+	for name, col := range db.Collections {
+		out.Collections[name] = col
+	}
+	return out
+}
@@ -1,76 +1,239 @@
 package core
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
 
-// Document → un documento JSON que contiene múltiples objetos
+	idx "machDB/src/internal/index"
+)
+
+// Document → un documento JSON que contiene múltiples objetos.
+//
+// Un objeto ya insertado no se modifica: se sustituye por una versión nueva en
+// su posición de Objects, e insertar añade al final. Quitar objetos crea un
+// slice nuevo. Así quien copia Objects (solo los punteros) con el documento
+// tomado tiene un snapshot que sigue igual después de soltarlo.
+//
+// NextID es el ID que recibirá el próximo objeto. Se guarda con el documento y
+// solo crece, así que un ID no se repite aunque se borre su objeto o se
+// recargue de disco.
+//
+// LSN es el del último snapshot en disco que lo incluye: las mutaciones del
+// journal con LSN no mayor ya están en sus objetos (ver engine.Replay).
+//
+// Cada documento lleva su propio shard del índice y la posición de cada ID en
+// Objects (para GetObjectByID en O(1)), que los métodos mantienen al día. El
+// RWMutex protege Objects, NextID, el shard y las posiciones; los métodos no lo
+// toman, lo hace quien los llama (el engine).
 type Document struct {
-	Name      string
-	Objects   []*Object `bson:"objects"`
-	nextObjID int       `bson:"-"`
+	sync.RWMutex `json:"-"`
+	Name         string
+	Objects      []*Object `bson:"objects"`
+	NextID       int       `json:"next_id"`
+	LSN          uint64    `json:"lsn"`
+	shard        *idx.Shard
+	pos          map[int]int
+}
+
+// UnmarshalJSON → acepta también las claves NextID y LSN de los snapshots
+// guardados antes de next_id y lsn
+func (d *Document) UnmarshalJSON(data []byte) error {
+	type fields Document
+	var raw struct {
+		*fields
+		LegacyNextID *int    `json:"NextID"`
+		LegacyLSN    *uint64 `json:"LSN"`
+	}
+	raw.fields = (*fields)(d)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.LegacyNextID != nil {
+		d.NextID = *raw.LegacyNextID
+	}
+	if raw.LegacyLSN != nil {
+		d.LSN = *raw.LegacyLSN
+	}
+	return nil
 }
 
 func NewDocument(name string) *Document {
 	return &Document{
-		Name:      name,
-		Objects:   []*Object{},
-		nextObjID: 0,
+		Name:    name,
+		Objects: []*Object{},
+		NextID:  0,
+		shard:   idx.NewShard(),
+		pos:     make(map[int]int),
 	}
 }
 
-// InsertObject -> inserta 1 objeto y devuelve su id asignado
+// Index -> el shard del índice del documento (solo lectura)
+func (d *Document) Index() *idx.Shard {
+	return d.shard
+}
+
+// Reindex -> reconstruye el shard y las posiciones desde Objects (por ejemplo
+// tras cargar de disco) y sube NextID por encima del mayor ID si hace falta
+// (archivos guardados antes de que se persistiera)
+func (d *Document) Reindex() {
+	d.shard = idx.NewShard()
+	d.pos = make(map[int]int, len(d.Objects))
+	for n, obj := range d.Objects {
+		d.shard.Add(obj.ID, obj.Fields)
+		d.pos[obj.ID] = n
+		if obj.ID >= d.NextID {
+			d.NextID = obj.ID + 1
+		}
+	}
+}
+
+// InsertObject -> inserta 1 objeto, lo indexa y devuelve su id asignado
 func (d *Document) InsertObject(fields map[string]interface{}) int {
-	obj := NewObject(d.nextObjID, fields)
+	if d.shard == nil {
+		d.Reindex()
+	}
+	obj := NewObject(d.NextID, fields)
 	d.Objects = append(d.Objects, obj)
-	d.nextObjID++
+	d.shard.Add(obj.ID, fields)
+	d.pos[obj.ID] = len(d.Objects) - 1
+	d.NextID++
 	return obj.ID
 }
 
-// InsertObjects -> inserta varios objetos y devuelve slice de ids
+// InsertObjects -> inserta varios objetos y devuelve slice de ids. El shard se
+// actualiza una sola vez con todo el lote (ver idx.Shard.AddAll)
 func (d *Document) InsertObjects(objs []map[string]interface{}) []int {
+	if d.shard == nil {
+		d.Reindex()
+	}
 	ids := make([]int, 0, len(objs))
 	for _, f := range objs {
-		ids = append(ids, d.InsertObject(f))
+		obj := NewObject(d.NextID, f)
+		d.Objects = append(d.Objects, obj)
+		d.pos[obj.ID] = len(d.Objects) - 1
+		ids = append(ids, obj.ID)
+		d.NextID++
 	}
+	d.shard.AddAll(ids, objs)
 	return ids
 }
 
-// GetObjectByID -> devuelve puntero al objeto o nil
+// Clone -> copia del documento con copias de sus objetos; conserva el contador
+// de IDs, así que los próximos InsertObject asignan los mismos IDs que el original
+// (quien llama tiene el documento tomado)
+func (d *Document) Clone() *Document {
+	objs := make([]*Object, len(d.Objects))
+	for i, o := range d.Objects {
+		objs[i] = o.Clone()
+	}
+	out := &Document{Name: d.Name, Objects: objs, NextID: d.NextID}
+	out.Reindex()
+	return out
+}
+
+// Copy -> copia para leer sin lock (volcarla a disco, exportarla): un slice de
+// objetos propio con las mismas versiones, NextID y LSN, sin índice (quien llama
+// tiene el documento tomado)
+func (d *Document) Copy() *Document {
+	objs := make([]*Object, len(d.Objects))
+	copy(objs, d.Objects)
+	return &Document{Name: d.Name, Objects: objs, NextID: d.NextID, LSN: d.LSN}
+}
+
+// Adopt -> sustituye los objetos, el contador y el índice de d por los de from,
+// que deja de usarse (quien llama tiene d tomado en escritura)
+func (d *Document) Adopt(from *Document) {
+	d.Objects = from.Objects
+	d.NextID = from.NextID
+	d.shard = from.shard
+	d.pos = from.pos
+}
+
+// GetObjectByID -> devuelve puntero al objeto o nil. Usa las posiciones; solo
+// recorre Objects si el documento aún no se ha indexado (recién decodificado)
 func (d *Document) GetObjectByID(id int) *Object {
+	if d.pos == nil {
+		for _, o := range d.Objects {
+			if o.ID == id {
+				return o
+			}
+		}
+		return nil
+	}
+	n, ok := d.pos[id]
+	if !ok {
+		return nil
+	}
+	return d.Objects[n]
+}
+
+// FindObjects -> devuelve los objetos que cumplan filter (sin copiar). Si filter
+// pide un id se resuelve con las posiciones, sin recorrer Objects
+func (d *Document) FindObjects(filter map[string]interface{}) []*Object {
+	if id, ok := filterID(filter); ok {
+		if o := d.GetObjectByID(id); o != nil && matchesFilter(o, filter) {
+			return []*Object{o}
+		}
+		return nil
+	}
+	var objs []*Object
 	for _, o := range d.Objects {
-		if o.ID == id {
-			return o
+		if matchesFilter(o, filter) {
+			objs = append(objs, o)
 		}
 	}
-	return nil
+	return objs
+}
+
+// ReplaceObjects -> sustituye por ID los objetos de versions (versiones nuevas de
+// objetos del documento) en su posición; los IDs que no están se ignoran
+func (d *Document) ReplaceObjects(versions map[int]*Object) {
+	if len(versions) == 0 {
+		return
+	}
+	if d.shard == nil || d.pos == nil {
+		d.Reindex()
+	}
+	for id, v := range versions {
+		n, ok := d.pos[id]
+		if !ok {
+			continue
+		}
+		d.shard.Update(id, d.Objects[n].Fields, v.Fields)
+		d.Objects[n] = v
+	}
 }
 
 // ModifyObjects -> aplica updates a objetos que cumplan filter (filter: map[key]value)
 // soporta filter {"id": 0} para buscar por id
 func (d *Document) ModifyObject(id int, newData map[string]interface{}) error {
-	for i, obj := range d.Objects {
-		if obj.ID == id {
-			// Actualiza solo las claves que estén en newData
-			for k, v := range newData {
-				d.Objects[i].Fields[k] = v
-			}
-			return nil
-		}
+	obj := d.GetObjectByID(id)
+	if obj == nil {
+		return fmt.Errorf("objeto con id %d no encontrado", id)
+	}
+	// Actualiza solo las claves que estén en newData
+	v := obj.Clone()
+	for k, val := range newData {
+		v.Fields[k] = val
 	}
-	return fmt.Errorf("objeto con id %d no encontrado", id)
+	d.ReplaceObjects(map[int]*Object{id: v})
+	return nil
 }
 func (d *Document) ModifyObjects(filter map[string]interface{}, updates map[string]interface{}) error {
-	found := false
-	for _, obj := range d.Objects {
-		if matchesFilter(obj, filter) {
-			for k, v := range updates {
-				obj.Fields[k] = v
-			}
-			found = true
+	versions := make(map[int]*Object)
+	for _, obj := range d.FindObjects(filter) {
+		v := obj.Clone()
+		for k, val := range updates {
+			v.Fields[k] = val
 		}
+		versions[obj.ID] = v
 	}
-	if !found {
+	if len(versions) == 0 {
 		return fmt.Errorf("no objects match the filter")
 	}
+	d.ReplaceObjects(versions)
 	return nil
 }
 
@@ -81,8 +244,15 @@ func (d *Document) DeleteObjects(filter map[string]interface{}) error {
 	for _, obj := range d.Objects {
 		if matchesFilter(obj, filter) {
 			found = true
+			if d.shard != nil {
+				d.shard.Remove(obj.ID, obj.Fields)
+			}
+			delete(d.pos, obj.ID)
 			continue
 		}
+		if d.pos != nil {
+			d.pos[obj.ID] = len(newObjs)
+		}
 		newObjs = append(newObjs, obj)
 	}
 	if !found {
@@ -93,12 +263,31 @@ func (d *Document) DeleteObjects(filter map[string]interface{}) error {
 }
 func (d *Document) Print() {
 	fmt.Printf("=== Documento: %s ===\n", d.Name)
-	for id, obj := range d.Objects {
-		fmt.Printf("ID: %d\n", id)
-		for k, v := range obj.Fields {
-			fmt.Printf("  %s: %v\n", k, v)
+	for _, obj := range d.Objects {
+		fmt.Printf("ID: %d\n", obj.ID)
+		keys := make([]string, 0, len(obj.Fields))
+		for k := range obj.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("  %s: %v\n", k, obj.Fields[k])
+		}
+	}
+}
+
+// filterID -> el id que pide filter ("id" o "_id"), con la misma conversión que
+// matchesFilter
+func filterID(filter map[string]interface{}) (int, bool) {
+	for _, k := range []string{"id", "_id"} {
+		switch tv := filter[k].(type) {
+		case int:
+			return tv, true
+		case float64:
+			return int(tv), true
 		}
 	}
+	return 0, false
 }
 
 // helper
@@ -0,0 +1,36 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDocumentJSONKeys(t *testing.T) {
+	d := NewDocument("d")
+	d.InsertObjects([]map[string]interface{}{{"n": 1}, {"n": 2}})
+	d.LSN = 9
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"next_id":2`, `"lsn":9`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("%s has no %s", data, key)
+		}
+	}
+
+	tests := []string{
+		string(data),
+		`{"Name":"d","Objects":[{"id":0,"fields":{"n":1}},{"id":1,"fields":{"n":2}}],"NextID":2,"LSN":9}`,
+	}
+	for _, in := range tests {
+		var got Document
+		if err := json.Unmarshal([]byte(in), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != "d" || len(got.Objects) != 2 || got.NextID != 2 || got.LSN != 9 {
+			t.Errorf("%s decoded as Name=%q, %d objects, NextID=%d, LSN=%d", in, got.Name, len(got.Objects), got.NextID, got.LSN)
+		}
+	}
+}
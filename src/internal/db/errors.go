@@ -0,0 +1,12 @@
+package core
+
+import "errors"
+
+// Errores base de la jerarquía db/colección/documento. Se envuelven con %w
+// ("collection users not found") para que quien llame pueda distinguirlos con
+// errors.Is sin depender del texto.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	ErrBadKeys       = errors.New("unknown key kind")
+)
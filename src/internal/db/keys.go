@@ -0,0 +1,80 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Claves de una colección. Además del ID entero de cada objeto (único y nunca
+// reutilizado dentro de su documento), una colección puede dar a cada objeto
+// nuevo una clave global en el campo KeyField. No es "_id": ese nombre ya es un
+// alias del ID entero en los filtros y en los joins.
+const (
+	KeysNone     = ""
+	KeysObjectID = "objectid"
+	KeysUUID     = "uuid"
+
+	KeyField = "_key"
+)
+
+// KeyKinds son los valores aceptados por Collection.Keys (sin contar KeysNone).
+var KeyKinds = []string{KeysObjectID, KeysUUID}
+
+// ValidKeys → error si kind no es un tipo de clave conocido
+func ValidKeys(kind string) error {
+	switch kind {
+	case KeysNone, KeysObjectID, KeysUUID:
+		return nil
+	}
+	return fmt.Errorf("%w %q (objectid, uuid)", ErrBadKeys, kind)
+}
+
+// NewKey → clave nueva del tipo kind ("" si es KeysNone)
+func NewKey(kind string) string {
+	switch kind {
+	case KeysObjectID:
+		return newObjectID()
+	case KeysUUID:
+		return newUUID()
+	}
+	return ""
+}
+
+// Estado de los ObjectID de este proceso: un valor aleatorio fijo y un contador
+// que empieza en un punto aleatorio.
+var (
+	objectIDProcess [5]byte
+	objectIDCounter uint32
+)
+
+func init() {
+	var seed [4]byte
+	rand.Read(objectIDProcess[:])
+	rand.Read(seed[:])
+	objectIDCounter = binary.BigEndian.Uint32(seed[:])
+}
+
+// newObjectID → 12 bytes en hex al estilo de MongoDB: segundos unix (4), valor
+// del proceso (5) y contador (3). Se ordenan aproximadamente por creación.
+func newObjectID() string {
+	var b [12]byte
+	binary.BigEndian.PutUint32(b[0:4], uint32(time.Now().Unix()))
+	copy(b[4:9], objectIDProcess[:])
+	n := atomic.AddUint32(&objectIDCounter, 1)
+	b[9], b[10], b[11] = byte(n>>16), byte(n>>8), byte(n)
+	return hex.EncodeToString(b[:])
+}
+
+// newUUID → UUID versión 4 (aleatorio) en su forma canónica
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
@@ -1,6 +1,13 @@
 package core
 
-// Object → entidad dentro de un documento
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Object → entidad dentro de un documento. Es una versión inmutable: los cambios
+// crean otra (Clone + ReplaceObjects) y la anterior vive mientras algún lector
+// la tenga, luego la recoge el GC
 type Object struct {
 	ID     int                    `json:"id"`
 	Fields map[string]interface{} `json:"fields"`
@@ -12,3 +19,59 @@ func NewObject(id int, fields map[string]interface{}) *Object {
 		Fields: fields,
 	}
 }
+
+// Clone → copia del objeto con su propio mapa de campos (los valores anidados se
+// comparten), para entregarlo fuera del lock del engine
+func (o *Object) Clone() *Object {
+	fields := make(map[string]interface{}, len(o.Fields))
+	for k, v := range o.Fields {
+		fields[k] = v
+	}
+	return NewObject(o.ID, fields)
+}
+
+// UnmarshalJSON → decodifica con UseNumber para que los enteros vuelvan como int
+// y no como float64 tras un FlushToDisk/LoadFromDisk
+func (o *Object) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		ID     int                    `json:"id"`
+		Fields map[string]interface{} `json:"fields"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	o.ID = raw.ID
+	o.Fields = make(map[string]interface{}, len(raw.Fields))
+	for k, v := range raw.Fields {
+		o.Fields[k] = NormalizeNumber(v)
+	}
+	return nil
+}
+
+// NormalizeNumber → json.Number a int (si es entero) o float64, recursivo en mapas y slices
+func NormalizeNumber(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case json.Number:
+		if i, err := tv.Int64(); err == nil {
+			return int(i)
+		}
+		if f, err := tv.Float64(); err == nil {
+			return f
+		}
+		return tv.String()
+	case map[string]interface{}:
+		for k, e := range tv {
+			tv[k] = NormalizeNumber(e)
+		}
+		return tv
+	case []interface{}:
+		for n, e := range tv {
+			tv[n] = NormalizeNumber(e)
+		}
+		return tv
+	default:
+		return v
+	}
+}
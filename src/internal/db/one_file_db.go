@@ -0,0 +1,308 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Formato de archivo único (.machdb), todo en little endian:
+//
+//	header   magic "MACHDB\x00\x01" | version u32 | segment count u32 | table offset u64
+//	datos    segmentos uno tras otro (JSON de cada Document y de los metadatos)
+//	tabla    por segmento: kind u8 | db, collection, document (u16 len + bytes) |
+//	         offset u64 | length u64 | crc32 u32
+//	trailer  crc32 u32 de todo lo anterior
+//
+// Las bases de datos y colecciones vacías tienen su propio segmento para que
+// sobrevivan a un Save/Load. El de una base de datos va sin datos y el de una
+// colección lleva su CollectionMeta si tiene algo que guardar.
+const (
+	oneFileMagic   = "MACHDB\x00\x01"
+	oneFileVersion = 1
+	headerSize     = 8 + 4 + 4 + 8
+)
+
+const (
+	segDatabase   byte = 1
+	segCollection byte = 2
+	segDocument   byte = 3
+	segMeta       byte = 4
+)
+
+var ErrBadChecksum = errors.New("one file db: checksum mismatch")
+
+// OneFileDB → backend que guarda todas las bases de datos en un único archivo
+type OneFileDB struct {
+	Path string
+}
+
+// NewOneFileDB → constructor
+func NewOneFileDB(path string) *OneFileDB {
+	return &OneFileDB{Path: path}
+}
+
+type segment struct {
+	kind       byte
+	db         string
+	collection string
+	document   string
+	offset     uint64
+	length     uint64
+	crc        uint32
+}
+
+// Save → escribe databases y meta (metadatos de índices, opacos para este paquete)
+// en un .tmp y lo renombra sobre Path
+func (f *OneFileDB) Save(databases map[string]*Database, meta json.RawMessage) error {
+	var data bytes.Buffer
+	var segs []segment
+
+	add := func(kind byte, dbName, colName, docName string, payload []byte) {
+		segs = append(segs, segment{
+			kind:       kind,
+			db:         dbName,
+			collection: colName,
+			document:   docName,
+			offset:     uint64(headerSize + data.Len()),
+			length:     uint64(len(payload)),
+			crc:        crc32.ChecksumIEEE(payload),
+		})
+		data.Write(payload)
+	}
+
+	for _, dbName := range sortedKeys(databases) {
+		database := databases[dbName]
+		add(segDatabase, dbName, "", "", nil)
+		for _, colName := range sortedKeys(database.Collections) {
+			col := database.Collections[colName]
+			var colMeta []byte
+			if col.Keys != KeysNone {
+				payload, err := json.Marshal(CollectionMeta{Keys: col.Keys})
+				if err != nil {
+					return err
+				}
+				colMeta = payload
+			}
+			add(segCollection, dbName, colName, "", colMeta)
+			for _, docName := range sortedKeys(col.Documents) {
+				payload, err := json.Marshal(col.Documents[docName])
+				if err != nil {
+					return err
+				}
+				add(segDocument, dbName, colName, docName, payload)
+			}
+		}
+	}
+	if len(meta) > 0 {
+		add(segMeta, "", "", "", meta)
+	}
+
+	var out bytes.Buffer
+	out.WriteString(oneFileMagic)
+	binary.Write(&out, binary.LittleEndian, uint32(oneFileVersion))
+	binary.Write(&out, binary.LittleEndian, uint32(len(segs)))
+	binary.Write(&out, binary.LittleEndian, uint64(headerSize+data.Len()))
+	out.Write(data.Bytes())
+	for _, s := range segs {
+		out.WriteByte(s.kind)
+		for _, name := range []string{s.db, s.collection, s.document} {
+			if len(name) > 0xFFFF {
+				return fmt.Errorf("one file db: name too long: %.32s...", name)
+			}
+			binary.Write(&out, binary.LittleEndian, uint16(len(name)))
+			out.WriteString(name)
+		}
+		binary.Write(&out, binary.LittleEndian, s.offset)
+		binary.Write(&out, binary.LittleEndian, s.length)
+		binary.Write(&out, binary.LittleEndian, s.crc)
+	}
+	binary.Write(&out, binary.LittleEndian, crc32.ChecksumIEEE(out.Bytes()))
+
+	tmpPath := f.Path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(out.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, f.Path); err != nil {
+		return err
+	}
+	return SyncDir(filepath.Dir(f.Path))
+}
+
+// SyncDir → fsync del directorio dir, para que los archivos creados, renombrados
+// o borrados dentro de él sobrevivan a un crash
+func SyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Load → lee Path y devuelve las bases de datos y los metadatos guardados.
+// Si el archivo no existe devuelve un mapa vacío.
+func (f *OneFileDB) Load() (map[string]*Database, json.RawMessage, error) {
+	databases := make(map[string]*Database)
+
+	raw, err := os.ReadFile(f.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return databases, nil, nil
+		}
+		return nil, nil, err
+	}
+	if len(raw) < headerSize+4 || string(raw[:8]) != oneFileMagic {
+		return nil, nil, fmt.Errorf("one file db: %s is not a machDB file", f.Path)
+	}
+	body, sum := raw[:len(raw)-4], binary.LittleEndian.Uint32(raw[len(raw)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, nil, ErrBadChecksum
+	}
+
+	version := binary.LittleEndian.Uint32(raw[8:12])
+	if version != oneFileVersion {
+		return nil, nil, fmt.Errorf("one file db: unsupported version %d", version)
+	}
+	count := binary.LittleEndian.Uint32(raw[12:16])
+	tableOffset := binary.LittleEndian.Uint64(raw[16:24])
+	if tableOffset < headerSize || tableOffset > uint64(len(body)) {
+		return nil, nil, fmt.Errorf("one file db: bad segment table offset")
+	}
+
+	table := bytes.NewReader(body[tableOffset:])
+	var meta json.RawMessage
+	for n := uint32(0); n < count; n++ {
+		s, err := readSegment(table)
+		if err != nil {
+			return nil, nil, fmt.Errorf("one file db: segment %d: %w", n, err)
+		}
+		// Sin sumar offset+length, que con valores corruptos puede desbordar
+		if s.offset < headerSize || s.length > tableOffset || s.offset > tableOffset-s.length {
+			return nil, nil, fmt.Errorf("one file db: segment %d out of bounds", n)
+		}
+		payload := body[s.offset : s.offset+s.length]
+		if crc32.ChecksumIEEE(payload) != s.crc {
+			return nil, nil, ErrBadChecksum
+		}
+
+		switch s.kind {
+		case segDatabase:
+			databases[s.db] = NewDatabase(s.db)
+		case segCollection:
+			database, ok := databases[s.db]
+			if !ok {
+				return nil, nil, fmt.Errorf("one file db: collection %s before database %s", s.collection, s.db)
+			}
+			if err := database.CreateCollection(s.collection); err != nil {
+				return nil, nil, err
+			}
+			if len(payload) > 0 {
+				var colMeta CollectionMeta
+				if err := json.Unmarshal(payload, &colMeta); err != nil {
+					return nil, nil, fmt.Errorf("one file db: %s/%s: %w", s.db, s.collection, err)
+				}
+				database.Collections[s.collection].Keys = colMeta.Keys
+			}
+		case segDocument:
+			database, ok := databases[s.db]
+			if !ok {
+				return nil, nil, fmt.Errorf("one file db: document %s before database %s", s.document, s.db)
+			}
+			col, err := database.GetCollection(s.collection)
+			if err != nil {
+				return nil, nil, err
+			}
+			var doc Document
+			if err := json.Unmarshal(payload, &doc); err != nil {
+				return nil, nil, fmt.Errorf("one file db: %s/%s/%s: %w", s.db, s.collection, s.document, err)
+			}
+			doc.Name = s.document
+			if doc.Objects == nil {
+				doc.Objects = []*Object{}
+			}
+			col.Documents[s.document] = &doc
+		case segMeta:
+			meta = append(json.RawMessage(nil), payload...)
+		default:
+			return nil, nil, fmt.Errorf("one file db: unknown segment kind %d", s.kind)
+		}
+	}
+	return databases, meta, nil
+}
+
+func readSegment(r io.Reader) (segment, error) {
+	var s segment
+	var kind [1]byte
+	if _, err := io.ReadFull(r, kind[:]); err != nil {
+		return s, err
+	}
+	s.kind = kind[0]
+	names := make([]string, 3)
+	for n := range names {
+		var l uint16
+		if err := binary.Read(r, binary.LittleEndian, &l); err != nil {
+			return s, err
+		}
+		buf := make([]byte, l)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return s, err
+		}
+		names[n] = string(buf)
+	}
+	s.db, s.collection, s.document = names[0], names[1], names[2]
+	for _, v := range []*uint64{&s.offset, &s.length} {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return s, err
+		}
+	}
+	if err := binary.Read(r, binary.LittleEndian, &s.crc); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// IsOneFileDB → true si path existe y empieza con la firma del formato
+func IsOneFileDB(path string) bool {
+	fh, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer fh.Close()
+	magic := make([]byte, len(oneFileMagic))
+	if _, err := io.ReadFull(fh, magic); err != nil {
+		return false
+	}
+	return string(magic) == oneFileMagic
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,200 @@
+package core
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// sampleDatabases: una base de datos con una colección con claves y documentos
+// de valores variados, una colección vacía y una base de datos vacía.
+func sampleDatabases(t *testing.T) map[string]*Database {
+	t.Helper()
+	shop := NewDatabase("shop")
+	for _, name := range []string{"users", "empty"} {
+		if err := shop.CreateCollection(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	users, _ := shop.GetCollection("users")
+	users.Keys = KeysUUID
+	if err := users.CreateDocument("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.CreateDocument("b"); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := users.GetDocument("a")
+	a.InsertObjects([]map[string]interface{}{
+		{"name": "ana", "age": 30, "score": 1.5, "vip": true, "note": nil},
+		{"name": "bob", "tags": []interface{}{"x", 2}, "address": map[string]interface{}{"city": "Lima", "zip": 15001}},
+		{"name": "cid"},
+	})
+	if err := a.DeleteObjects(map[string]interface{}{"name": "cid"}); err != nil {
+		t.Fatal(err)
+	}
+	a.LSN = 7
+	return map[string]*Database{"shop": shop, "empty": NewDatabase("empty")}
+}
+
+// summary deja de cada base de datos lo que debe sobrevivir a Save/Load.
+func summary(databases map[string]*Database) map[string]interface{} {
+	out := make(map[string]interface{})
+	for dbName, database := range databases {
+		for colName, col := range database.Collections {
+			out[dbName+"/"+colName] = col.Keys
+			for docName, doc := range col.Documents {
+				objs := make([]Object, len(doc.Objects))
+				for n, obj := range doc.Objects {
+					objs[n] = *obj
+				}
+				out[dbName+"/"+colName+"/"+docName] = []interface{}{objs, doc.NextID, doc.LSN}
+			}
+		}
+		out[dbName] = len(database.Collections)
+	}
+	return out
+}
+
+func TestOneFileDBRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shop.machdb")
+	f := NewOneFileDB(path)
+	want := sampleDatabases(t)
+	meta := json.RawMessage(`{"indexed_fields":{"name":2}}`)
+	if err := f.Save(want, meta); err != nil {
+		t.Fatal(err)
+	}
+	if !IsOneFileDB(path) {
+		t.Error("IsOneFileDB = false on a saved file")
+	}
+
+	got, gotMeta, err := f.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(summary(got), summary(want)) {
+		t.Errorf("Load =\n%v\nwant\n%v", summary(got), summary(want))
+	}
+	if string(gotMeta) != string(meta) {
+		t.Errorf("meta = %s, want %s", gotMeta, meta)
+	}
+
+	// el contador de IDs sigue donde estaba: cid (ID 2) no se reutiliza
+	doc := got["shop"].Collections["users"].Documents["a"]
+	doc.Reindex()
+	if id := doc.InsertObject(map[string]interface{}{"name": "dan"}); id != 3 {
+		t.Errorf("next ID after Load = %d, want 3", id)
+	}
+}
+
+func TestOneFileDBLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	databases, meta, err := NewOneFileDB(filepath.Join(dir, "missing.machdb")).Load()
+	if err != nil || len(databases) != 0 || meta != nil {
+		t.Errorf("Load of a missing file = %v, %s, %v; want an empty map", databases, meta, err)
+	}
+
+	notOurs := filepath.Join(dir, "notes.machdb")
+	if err := os.WriteFile(notOurs, []byte("just some text that is long enough"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if IsOneFileDB(notOurs) {
+		t.Error("IsOneFileDB = true on a text file")
+	}
+	if _, _, err := NewOneFileDB(notOurs).Load(); err == nil {
+		t.Error("Load of a text file: want error")
+	}
+
+	path := filepath.Join(dir, "shop.machdb")
+	if err := NewOneFileDB(path).Save(sampleDatabases(t), nil); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[headerSize+2] ^= 0xff
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := NewOneFileDB(path).Load(); !errors.Is(err, ErrBadChecksum) {
+		t.Errorf("Load of a corrupted file = %v, want ErrBadChecksum", err)
+	}
+}
+
+// Un archivo truncado o con la cabecera o la tabla de segmentos corruptas da un
+// error (nunca un panic), aunque el trailer cuadre con lo corrupto.
+func TestOneFileDBLoadCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shop.machdb")
+	if err := NewOneFileDB(path).Save(sampleDatabases(t), nil); err != nil {
+		t.Fatal(err)
+	}
+	good, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tableOffset := binary.LittleEndian.Uint64(good[16:24])
+	// posición del offset del primer segmento: kind y tres nombres u16+bytes
+	segOffset := int(tableOffset) + 1
+	for n := 0; n < 3; n++ {
+		segOffset += 2 + int(binary.LittleEndian.Uint16(good[segOffset:]))
+	}
+
+	// resign rehace el trailer para que el checksum no delate el cambio
+	resign := func(raw []byte) []byte {
+		body := raw[:len(raw)-4]
+		binary.LittleEndian.PutUint32(raw[len(raw)-4:], crc32.ChecksumIEEE(body))
+		return raw
+	}
+	tests := []struct {
+		name   string
+		mangle func(raw []byte) []byte
+	}{
+		{"truncated to the header", func(raw []byte) []byte { return raw[:headerSize] }},
+		{"truncated in the middle", func(raw []byte) []byte { return raw[:len(raw)/2] }},
+		{"truncated and re-signed", func(raw []byte) []byte { return resign(raw[:len(raw)-10]) }},
+		{"table offset past the end", func(raw []byte) []byte {
+			binary.LittleEndian.PutUint64(raw[16:24], uint64(len(raw)))
+			return resign(raw)
+		}},
+		{"table offset inside the header", func(raw []byte) []byte {
+			binary.LittleEndian.PutUint64(raw[16:24], 4)
+			return resign(raw)
+		}},
+		{"more segments than the table", func(raw []byte) []byte {
+			binary.LittleEndian.PutUint32(raw[12:16], 1000)
+			return resign(raw)
+		}},
+		{"segment offset overflows", func(raw []byte) []byte {
+			binary.LittleEndian.PutUint64(raw[segOffset:], ^uint64(0))
+			binary.LittleEndian.PutUint64(raw[segOffset+8:], 2)
+			return resign(raw)
+		}},
+		{"segment length overflows", func(raw []byte) []byte {
+			binary.LittleEndian.PutUint64(raw[segOffset+8:], ^uint64(0))
+			return resign(raw)
+		}},
+		{"segment inside the header", func(raw []byte) []byte {
+			binary.LittleEndian.PutUint64(raw[segOffset:], 0)
+			return resign(raw)
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := filepath.Join(dir, "bad.machdb")
+			if err := os.WriteFile(p, tc.mangle(append([]byte(nil), good...)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if databases, _, err := NewOneFileDB(p).Load(); err == nil {
+				t.Errorf("Load = %v, want error", databases)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"errors"
+
+	db "machDB/src/internal/db"
+)
+
+// BulkDoc es un lote de objetos para un documento, usado por import.
+type BulkDoc struct {
+	Collection string
+	Document   string
+	Objects    []map[string]interface{}
+}
+
+// BulkInsert crea la base de datos, colecciones y documentos que falten e inserta
+// todos los lotes. Cada lote se inserta con los locks de su documento, como
+// InsertObjects, y el shard del documento se construye de una vez con todo el
+// lote en vez de objeto por objeto. e.mu solo se toma en escritura si hay que
+// crear la base de datos. Devuelve cuántos objetos se insertaron.
+func (e *Engine) BulkInsert(dbName string, docs []BulkDoc) (int, error) {
+	e.mu.RLock()
+	_, exists := e.Databases[dbName]
+	e.mu.RUnlock()
+	if !exists {
+		err := e.CreateDatabase(dbName)
+		if err != nil && !errors.Is(err, db.ErrAlreadyExists) {
+			return 0, err
+		}
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	total := 0
+	for _, bd := range docs {
+		// Lo que ya exista (o cree otro escritor entretanto) se usa tal cual
+		err := e.createCollectionLocked(dbName, bd.Collection)
+		if err != nil && !errors.Is(err, db.ErrAlreadyExists) {
+			return total, err
+		}
+		err = e.createDocumentLocked(dbName, bd.Collection, bd.Document)
+		if err != nil && !errors.Is(err, db.ErrAlreadyExists) {
+			return total, err
+		}
+		if len(bd.Objects) == 0 {
+			continue
+		}
+		ids, err := e.insertObjectsLocked(dbName, bd.Collection, bd.Document, bd.Objects)
+		if err != nil {
+			return total, err
+		}
+		total += len(ids)
+	}
+	return total, nil
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	db "machDB/src/internal/db"
+)
+
+// Lo cargado con BulkInsert se encuentra por el índice igual que lo insertado
+// objeto a objeto: en documentos nuevos y en uno que ya tenía objetos.
+func TestBulkInsertIndexes(t *testing.T) {
+	e := newTestEngine(t, "shop", docs{
+		"people/a": {{"name": "ana", "age": 17}},
+	})
+	n, err := e.BulkInsert("shop", []BulkDoc{
+		{Collection: "people", Document: "a", Objects: []map[string]interface{}{
+			{"name": "bob", "age": 18}, {"name": "cid", "age": 30},
+		}},
+		{Collection: "people", Document: "b", Objects: []map[string]interface{}{
+			{"name": "dan", "age": 18}, {"name": "eva", "age": "18"},
+		}},
+		{Collection: "staff", Document: "s", Objects: []map[string]interface{}{
+			{"name": "fay", "age": 18},
+		}},
+		{Collection: "staff", Document: "empty"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("BulkInsert = %d, want 5", n)
+	}
+
+	tests := []struct {
+		name string
+		find func() ([]string, error)
+		want []string
+	}{
+		{"equal", func() ([]string, error) { return objNames(e.Find("age", 18, "shop")) }, []string{"bob", "dan", "fay"}},
+		{"equal string", func() ([]string, error) { return objNames(e.Find("age", "18", "shop")) }, []string{"eva"}},
+		{"one collection", func() ([]string, error) { return objNames(e.Find("age", 18, "shop", "people")) }, []string{"bob", "dan"}},
+		{"range", func() ([]string, error) { return objNames(e.FindByQuery("age>=18", "shop")) }, []string{"bob", "cid", "dan", "fay"}},
+		{"older object", func() ([]string, error) { return objNames(e.FindByQuery("age<18", "shop")) }, []string{"ana"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.find()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("found %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// el shard construido por lotes es el mismo que al reindexar desde cero
+	doc := e.Databases["shop"].Collections["people"].Documents["a"]
+	built := doc.Index().Inverted
+	doc.Reindex()
+	if !reflect.DeepEqual(built, doc.Index().Inverted) {
+		t.Errorf("bulk index = %v, want %v", built, doc.Index().Inverted)
+	}
+}
+
+// objNames ordena los name de objs.
+func objNames(objs []*db.Object, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, obj := range objs {
+		names = append(names, obj.Fields["name"].(string))
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+// Los benchmarks de concurrencia se comparan entre sí y con varios -cpu:
+//
+//	go test ./src/internal/engine -run '^$' -bench Concurrent -cpu 1,2,4,8
+//
+// Con un lock global todas las variantes de BenchmarkConcurrentInsert escalan
+// igual de mal; con la jerarquía de locks solo "same-document" se serializa.
+
+const benchWorkers = 16
+
+// benchEngine crea dbs bases de datos con cols colecciones de docs documentos
+// cada una: "db0"/"c0"/"d0"...
+func benchEngine(b *testing.B, dbs, cols, docs int) *Engine {
+	b.Helper()
+	e := NewEngine()
+	for i := 0; i < dbs; i++ {
+		dbName := fmt.Sprintf("db%d", i)
+		if err := e.CreateDatabase(dbName); err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < cols; j++ {
+			colName := fmt.Sprintf("c%d", j)
+			if err := e.CreateCollection(dbName, colName); err != nil {
+				b.Fatal(err)
+			}
+			for k := 0; k < docs; k++ {
+				if err := e.CreateDocument(dbName, colName, fmt.Sprintf("d%d", k)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+	return e
+}
+
+// BenchmarkConcurrentInsert inserta en paralelo; cada goroutine escribe siempre
+// en el mismo destino, que según la variante comparte o no con las demás.
+func BenchmarkConcurrentInsert(b *testing.B) {
+	cases := []struct {
+		name            string
+		dbs, cols, docs int
+		target          func(w int) (string, string, string)
+	}{
+		{"same-document", 1, 1, 1, func(w int) (string, string, string) {
+			return "db0", "c0", "d0"
+		}},
+		{"separate-documents", 1, 1, benchWorkers, func(w int) (string, string, string) {
+			return "db0", "c0", fmt.Sprintf("d%d", w)
+		}},
+		{"separate-collections", 1, benchWorkers, 1, func(w int) (string, string, string) {
+			return "db0", fmt.Sprintf("c%d", w), "d0"
+		}},
+		{"separate-databases", benchWorkers, 1, 1, func(w int) (string, string, string) {
+			return fmt.Sprintf("db%d", w), "c0", "d0"
+		}},
+	}
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			e := benchEngine(b, tc.dbs, tc.cols, tc.docs)
+			var next int32
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				w := int(atomic.AddInt32(&next, 1)-1) % benchWorkers
+				dbName, colName, docName := tc.target(w)
+				n := 0
+				for pb.Next() {
+					fields := map[string]interface{}{"n": n, "worker": w, "group": n % 10}
+					if _, err := e.InsertObject(dbName, colName, docName, fields); err != nil {
+						b.Error(err)
+						return
+					}
+					n++
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkConcurrentFindInsert busca en c0 mientras otras goroutines insertan
+// en sus propias colecciones: las búsquedas no deberían frenar a los escritores.
+func BenchmarkConcurrentFindInsert(b *testing.B) {
+	e := benchEngine(b, 1, benchWorkers, 1)
+	for n := 0; n < 1000; n++ {
+		e.InsertObject("db0", "c0", "d0", map[string]interface{}{"n": n, "group": n % 100})
+	}
+	var next int32
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		w := int(atomic.AddInt32(&next, 1)-1) % benchWorkers
+		colName := fmt.Sprintf("c%d", w)
+		n := 0
+		for pb.Next() {
+			if w%2 == 0 {
+				if _, err := e.FindByQuery("group:3", "db0", "c0"); err != nil {
+					b.Error(err)
+					return
+				}
+				continue
+			}
+			if _, err := e.InsertObject("db0", colName, "d0", map[string]interface{}{"n": n}); err != nil {
+				b.Error(err)
+				return
+			}
+			n++
+		}
+	})
+}
@@ -0,0 +1,488 @@
+package engine
+
+import (
+	"fmt"
+	db "machDB/src/internal/db"
+	idx "machDB/src/internal/index"
+	"sync"
+)
+
+// Engine guarda las bases de datos en memoria; el índice invertido está
+// repartido en un shard por documento (ver db.Document.Index).
+//
+// Los locks forman una jerarquía y siempre se toman de arriba abajo (y, entre
+// hermanos, en orden de nombre):
+//
+//	Engine.mu      el mapa Databases; en escritura solo para crear o borrar bases de datos
+//	db.Database    sus Collections; en escritura para crear o borrar colecciones
+//	db.Collection  sus Documents; en escritura para crear o borrar documentos
+//	db.Document    sus Objects y su shard; en escritura para insert, merge y modify
+//
+// Quien escribe en un nivel tiene tomados en lectura los de arriba hasta terminar
+// (también mientras registra en el journal), así que el trabajo sobre bases de
+// datos, colecciones o documentos distintos no se serializa, y las mutaciones que
+// dependen entre sí llegan al journal en el orden en que se aplican. Los métodos
+// *Locked esperan e.mu tomado (en escritura los de bases de datos) y toman ellos
+// los locks de abajo.
+//
+// Las lecturas (Find*, ListObjects) trabajan sobre un snapshot: con los locks de
+// lectura de su alcance solo copian los punteros de los objetos candidatos y los
+// sueltan antes de filtrar y copiar los objetos, así que los escritores no
+// esperan a que termine una búsqueda larga. Es MVCC por versiones de Object: los
+// escritores nunca cambian un objeto ya publicado, sino que instalan una versión
+// nueva en su lugar de Document.Objects (ver db.Document), y el snapshot sigue
+// viendo las que había al empezar. Las versiones sustituidas no
+// necesitan un recolector propio: en cuanto ningún snapshot las referencia las
+// libera el GC.
+type Engine struct {
+	Databases map[string]*db.Database `json:"databases"`
+	mu        sync.RWMutex
+	journal   Journal
+}
+
+func NewEngine() *Engine {
+	return &Engine{
+		Databases: make(map[string]*db.Database),
+	}
+}
+
+// CreateDatabase crea una DB en memoria usando tu struct Database
+func (e *Engine) CreateDatabase(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.createDatabaseLocked(name)
+}
+
+// createDatabaseLocked es CreateDatabase con e.mu ya tomado en escritura.
+func (e *Engine) createDatabaseLocked(name string) error {
+	if _, exists := e.Databases[name]; exists {
+		return fmt.Errorf("database %s %w", name, db.ErrAlreadyExists)
+	}
+	if err := e.record(Mutation{Op: MutCreateDatabase, DB: name}); err != nil {
+		return err
+	}
+	e.Databases[name] = db.NewDatabase(name)
+	return nil
+}
+
+// CreateCollection crea una colección dentro de una DB ya existente
+func (e *Engine) CreateCollection(dbName, colName string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.createCollectionLocked(dbName, colName)
+}
+
+func (e *Engine) createCollectionLocked(dbName, colName string) error {
+	database, ok := e.Databases[dbName]
+	if !ok {
+		return fmt.Errorf("database %s %w", dbName, db.ErrNotFound)
+	}
+	database.Lock()
+	defer database.Unlock()
+	if _, exists := database.Collections[colName]; exists {
+		return fmt.Errorf("collection %s %w", colName, db.ErrAlreadyExists)
+	}
+	if err := e.record(Mutation{Op: MutCreateCollection, DB: dbName, Collection: colName}); err != nil {
+		return err
+	}
+	return database.CreateCollection(colName)
+}
+
+// SetCollectionKeys elige las claves que reciben los objetos nuevos de la
+// colección en db.KeyField (db.KeysObjectID, db.KeysUUID o db.KeysNone para
+// ninguna). Los objetos que ya existen no cambian.
+func (e *Engine) SetCollectionKeys(dbName, colName, keys string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.setCollectionKeysLocked(dbName, colName, keys)
+}
+
+func (e *Engine) setCollectionKeysLocked(dbName, colName, keys string) error {
+	if err := db.ValidKeys(keys); err != nil {
+		return err
+	}
+	col, unlock, err := e.lockCollection(dbName, colName, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := e.record(Mutation{Op: MutSetKeys, DB: dbName, Collection: colName, Keys: keys}); err != nil {
+		return err
+	}
+	col.Keys = keys
+	return nil
+}
+
+// CreateDocument crea un documento (vacío) dentro de una colección
+func (e *Engine) CreateDocument(dbName, colName, docName string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.createDocumentLocked(dbName, colName, docName)
+}
+
+func (e *Engine) createDocumentLocked(dbName, colName, docName string) error {
+	col, unlock, err := e.lockCollection(dbName, colName, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, exists := col.Documents[docName]; exists {
+		return fmt.Errorf("document %s %w", docName, db.ErrAlreadyExists)
+	}
+	if err := e.record(Mutation{Op: MutCreateDocument, DB: dbName, Collection: colName, Document: docName}); err != nil {
+		return err
+	}
+	return col.CreateDocument(docName)
+}
+
+// InsertObject inserta y actualiza el índice. Devuelve el object ID asignado.
+func (e *Engine) InsertObject(dbName, colName, docName string, fields map[string]interface{}) (int, error) {
+	ids, err := e.InsertObjects(dbName, colName, docName, []map[string]interface{}{fields})
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// InsertObjects inserta varios objetos en el mismo documento y devuelve sus IDs en orden.
+// Si la colección tiene claves, los objetos de objs sin db.KeyField se sustituyen
+// por copias con su clave nueva.
+func (e *Engine) InsertObjects(dbName, colName, docName string, objs []map[string]interface{}) ([]int, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.insertObjectsLocked(dbName, colName, docName, objs)
+}
+
+func (e *Engine) insertObjectsLocked(dbName, colName, docName string, objs []map[string]interface{}) ([]int, error) {
+	doc, unlock, err := e.lockDocument(dbName, colName, docName, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	// Las claves se generan antes de registrar: al reproducir el journal los
+	// objetos ya las traen y no se generan otras
+	e.Databases[dbName].Collections[colName].AssignKeys(objs)
+	m := Mutation{Op: MutInsert, DB: dbName, Collection: colName, Document: docName, Objects: objs}
+	if err := e.record(m); err != nil {
+		return nil, err
+	}
+
+	// Inserta en Document, que indexa cada objeto en su shard
+	return doc.InsertObjects(objs), nil
+}
+
+// MergeObjects mezcla fields en los objetos que cumplan filter y reindexa los campos tocados.
+// Devuelve los IDs de los objetos afectados.
+func (e *Engine) MergeObjects(dbName, colName, docName string, filter, fields map[string]interface{}) ([]int, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.mergeObjectsLocked(dbName, colName, docName, filter, fields)
+}
+
+func (e *Engine) mergeObjectsLocked(dbName, colName, docName string, filter, fields map[string]interface{}) ([]int, error) {
+	m := Mutation{Op: MutMerge, DB: dbName, Collection: colName, Document: docName, Filter: filter, Fields: fields}
+	ids, err := e.applyUpdates(m)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no objects match the filter")
+	}
+	return ids, nil
+}
+
+// ModifyObjects aplica updates a los objetos que cumplan filter manteniendo el índice
+// al día. Un valor nil elimina el campo. Devuelve cuántos objetos se modificaron.
+func (e *Engine) ModifyObjects(dbName, colName, docName string, filter, updates map[string]interface{}) (int, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.modifyObjectsLocked(dbName, colName, docName, filter, updates)
+}
+
+func (e *Engine) modifyObjectsLocked(dbName, colName, docName string, filter, updates map[string]interface{}) (int, error) {
+	m := Mutation{Op: MutModify, DB: dbName, Collection: colName, Document: docName, Filter: filter, Fields: updates}
+	ids, err := e.applyUpdates(m)
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// applyUpdates crea una versión nueva de los objetos que cumplan m.Filter con
+// m.Fields aplicado y la instala en el documento, que reindexa los campos que
+// cambian. Con un filtro por id el coste no depende del tamaño del documento.
+// Solo se registra en el journal si hay objetos afectados. El llamador debe
+// tener e.mu tomado.
+func (e *Engine) applyUpdates(m Mutation) ([]int, error) {
+	doc, unlock, err := e.lockDocument(m.DB, m.Collection, m.Document, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	objs := doc.FindObjects(m.Filter)
+	if len(objs) == 0 {
+		return nil, nil
+	}
+	if err := e.record(m); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(objs))
+	versions := make(map[int]*db.Object, len(objs))
+	for _, obj := range objs {
+		next := obj.Clone()
+		for k, v := range m.Fields {
+			if v == nil {
+				delete(next.Fields, k)
+				continue
+			}
+			next.Fields[k] = v
+		}
+		versions[obj.ID] = next
+		ids = append(ids, obj.ID)
+	}
+	doc.ReplaceObjects(versions)
+	return ids, nil
+}
+
+// Find busca field = value dentro de dbName, restringido a collections si se indican.
+func (e *Engine) Find(field string, value interface{}, dbName string, collections ...string) ([]*db.Object, error) {
+	key := idx.KeyOf(value)
+	objs, err := e.collect(dbName, collections, func(d docView) []*db.Object {
+		return objectsByID(d.doc, d.doc.Index().Lookup(field, key))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("%w: value %s for field %s", ErrNoResults, key, field)
+	}
+	return cloneObjects(objs), nil
+}
+
+func (e *Engine) FindByQuery(query string, dbName string, collections ...string) ([]*db.Object, error) {
+	p, err := ParsePredicate(query)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := e.collect(dbName, collections, func(d docView) []*db.Object {
+		return objectsByID(d.doc, lookup(d.doc, p))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) == 0 {
+		return nil, ErrNoResults
+	}
+	return cloneObjects(objs), nil
+}
+
+// FindByQueries interseca (AND) los resultados de varias consultas ("campo:valor",
+// "campo>valor", "campo:lo..hi", ver ParsePredicate) dentro de dbName,
+// restringidos a collections si se indican.
+func (e *Engine) FindByQueries(queries []string, dbName string, collections ...string) ([]*db.Object, error) {
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("%w: no query given", ErrBadQuery)
+	}
+	preds := make([]Predicate, 0, len(queries))
+	for _, q := range queries {
+		p, err := ParsePredicate(q)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, p)
+	}
+
+	objs, err := e.collect(dbName, collections, func(d docView) []*db.Object {
+		var ids []int
+		for n, p := range preds {
+			found := lookup(d.doc, p)
+			if n == 0 {
+				ids = append(ids, found...)
+				continue
+			}
+			keep := make(map[int]bool, len(found))
+			for _, id := range found {
+				keep[id] = true
+			}
+			filtered := ids[:0]
+			for _, id := range ids {
+				if keep[id] {
+					filtered = append(filtered, id)
+				}
+			}
+			ids = filtered
+		}
+		return objectsByID(d.doc, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) == 0 {
+		return nil, ErrNoResults
+	}
+	return cloneObjects(objs), nil
+}
+
+// objectsByID resuelve ids a las versiones actuales de los objetos de doc (sin
+// copiar). Es el snapshot de una lectura: los objetos no cambian, así que se
+// pueden leer después de soltar los locks. El llamador debe tener doc tomado.
+func objectsByID(doc *db.Document, ids []int) []*db.Object {
+	objs := make([]*db.Object, 0, len(ids))
+	for _, id := range ids {
+		if obj := doc.GetObjectByID(id); obj != nil {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+// testHookSnapshot, si no es nil, se llama en cloneObjects: las lecturas ya
+// soltaron sus locks y aún no copiaron el snapshot. Los tests lo usan para
+// meter escrituras justo ahí.
+var testHookSnapshot func()
+
+// cloneObjects copia objs para entregarlos fuera del engine (quien los recibe
+// puede modificarlos sin tocar las versiones publicadas).
+func cloneObjects(objs []*db.Object) []*db.Object {
+	if testHookSnapshot != nil {
+		testHookSnapshot()
+	}
+	out := make([]*db.Object, len(objs))
+	for n, obj := range objs {
+		out[n] = obj.Clone()
+	}
+	return out
+}
+
+// ListObjects devuelve copias de los objetos de un documento, en orden.
+func (e *Engine) ListObjects(dbName, colName, docName string) ([]*db.Object, error) {
+	e.mu.RLock()
+	doc, unlock, err := e.lockDocument(dbName, colName, docName, false)
+	if err != nil {
+		e.mu.RUnlock()
+		return nil, err
+	}
+	objs := append([]*db.Object(nil), doc.Objects...)
+	unlock()
+	e.mu.RUnlock()
+
+	return cloneObjects(objs), nil
+}
+
+func (e *Engine) ListDatabases() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	dbNames := make([]string, 0, len(e.Databases))
+	for name := range e.Databases {
+		dbNames = append(dbNames, name)
+	}
+	return dbNames
+}
+func (e *Engine) ListCollections(dbName string) ([]string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	database, ok := e.Databases[dbName]
+	if !ok {
+		return nil, fmt.Errorf("database %s %w", dbName, db.ErrNotFound)
+	}
+	database.RLock()
+	defer database.RUnlock()
+
+	collections := make([]string, 0, len(database.Collections))
+	for colName := range database.Collections {
+		collections = append(collections, colName)
+	}
+
+	return collections, nil
+}
+func (e *Engine) ListDocuments(dbName, colName string) ([]string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	collection, unlock, err := e.lockCollection(dbName, colName, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	documents := make([]string, 0, len(collection.Documents))
+	for docName := range collection.Documents {
+		documents = append(documents, docName)
+	}
+
+	return documents, nil
+}
+
+// DeleteDatabase elimina una base de datos completa (con ella se van los shards
+// del índice de sus documentos).
+func (e *Engine) DeleteDatabase(dbName string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.deleteDatabaseLocked(dbName)
+}
+
+func (e *Engine) deleteDatabaseLocked(dbName string) error {
+	_, ok := e.Databases[dbName]
+	if !ok {
+		return fmt.Errorf("database %s %w", dbName, db.ErrNotFound)
+	}
+	if err := e.record(Mutation{Op: MutDeleteDatabase, DB: dbName}); err != nil {
+		return err
+	}
+	delete(e.Databases, dbName)
+	return nil
+}
+
+// DeleteCollection elimina una colección de una base de datos.
+func (e *Engine) DeleteCollection(dbName, colName string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.deleteCollectionLocked(dbName, colName)
+}
+
+func (e *Engine) deleteCollectionLocked(dbName, colName string) error {
+	database, ok := e.Databases[dbName]
+	if !ok {
+		return fmt.Errorf("database %s %w", dbName, db.ErrNotFound)
+	}
+	database.Lock()
+	defer database.Unlock()
+
+	_, ok = database.Collections[colName]
+	if !ok {
+		return fmt.Errorf("collection %s %w in database %s", colName, db.ErrNotFound, dbName)
+	}
+	if err := e.record(Mutation{Op: MutDeleteCollection, DB: dbName, Collection: colName}); err != nil {
+		return err
+	}
+	delete(database.Collections, colName)
+	return nil
+}
+
+// DeleteDocument elimina un documento de una colección.
+func (e *Engine) DeleteDocument(dbName, colName, docName string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.deleteDocumentLocked(dbName, colName, docName)
+}
+
+func (e *Engine) deleteDocumentLocked(dbName, colName, docName string) error {
+	collection, unlock, err := e.lockCollection(dbName, colName, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := collection.Documents[docName]; !ok {
+		return fmt.Errorf("document %s %w in collection %s", docName, db.ErrNotFound, colName)
+	}
+	if err := e.record(Mutation{Op: MutDeleteDocument, DB: dbName, Collection: colName, Document: docName}); err != nil {
+		return err
+	}
+	delete(collection.Documents, docName)
+	return nil
+}
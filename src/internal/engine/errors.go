@@ -0,0 +1,12 @@
+package engine
+
+import "errors"
+
+var (
+	// ErrNoResults indica una búsqueda válida que no encontró objetos.
+	ErrNoResults = errors.New("no results found")
+	// ErrBadQuery indica una consulta que no se puede interpretar.
+	ErrBadQuery = errors.New("bad query")
+	// ErrTxDone indica una operación sobre una transacción ya confirmada o descartada.
+	ErrTxDone = errors.New("transaction already finished")
+)
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	db "machDB/src/internal/db"
+	idx "machDB/src/internal/index"
+)
+
+// Filter es una expresión de búsqueda que el engine sabe ejecutar sin conocer su
+// sintaxis. Candidates reduce el espacio con los índices (ok=false si no puede y
+// hay que recorrer todo) y Match es el filtro residual que se aplica a cada candidato.
+type Filter interface {
+	Candidates(lookup func(Predicate) []idx.ObjectRef) (refs []idx.ObjectRef, ok bool)
+	Match(obj *db.Object) bool
+}
+
+// FindWhere ejecuta f dentro de dbName (y de collections si se indican). Los
+// locks de lectura solo se toman para sacar los candidatos, documento a
+// documento con su shard del índice; Match y las copias se hacen sobre ese
+// snapshot sin bloquear a los escritores.
+func (e *Engine) FindWhere(f Filter, dbName string, collections ...string) ([]*db.Object, error) {
+	objs, err := e.collect(dbName, collections, func(d docView) []*db.Object {
+		refs, ok := f.Candidates(func(p Predicate) []idx.ObjectRef {
+			return d.refs(lookup(d.doc, p))
+		})
+		if !ok {
+			return d.doc.Objects
+		}
+		ids := make([]int, len(refs))
+		for n, ref := range refs {
+			ids[n] = ref.ID
+		}
+		return objectsByID(d.doc, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*db.Object
+	for _, obj := range objs {
+		if f.Match(obj) {
+			results = append(results, obj.Clone())
+		}
+	}
+	if len(results) == 0 {
+		return nil, ErrNoResults
+	}
+	return results, nil
+}
+
+// refs convierte IDs de objetos de d en refs completas.
+func (d docView) refs(ids []int) []idx.ObjectRef {
+	refs := make([]idx.ObjectRef, len(ids))
+	for n, id := range ids {
+		refs[n] = idx.ObjectRef{DB: d.dbName, Collection: d.collection, Document: d.name, ID: id}
+	}
+	return refs
+}
@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+)
+
+// BenchmarkFind busca por un campo indexado variando por separado el tamaño del
+// documento y el de la respuesta: con las posiciones por ID de Document el
+// tiempo depende de results y apenas de objects.
+//
+//	go test ./src/internal/engine -run '^$' -bench Find
+func BenchmarkFind(b *testing.B) {
+	for _, objects := range []int{1000, 10000, 100000} {
+		for _, results := range []int{10, 100} {
+			b.Run(fmt.Sprintf("objects=%d/results=%d", objects, results), func(b *testing.B) {
+				e := benchEngine(b, 1, 1, 1)
+				objs := make([]map[string]interface{}, objects)
+				for n := range objs {
+					// hit solo en los últimos results objetos, el peor caso de un recorrido
+					objs[n] = map[string]interface{}{"n": n, "hit": n >= objects-results}
+				}
+				if _, err := e.InsertObjects("db0", "c0", "d0", objs); err != nil {
+					b.Fatal(err)
+				}
+				b.ResetTimer()
+				for n := 0; n < b.N; n++ {
+					found, err := e.Find("hit", true, "db0", "c0")
+					if err != nil {
+						b.Fatal(err)
+					}
+					if len(found) != results {
+						b.Fatalf("found %d objects, want %d", len(found), results)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"fmt"
+	db "machDB/src/internal/db"
+	"sort"
+)
+
+// lockCollection resuelve db/colección con e.mu ya tomado: deja la base de datos
+// en lectura y la colección en escritura si write (si no, en lectura). unlock
+// suelta ambas.
+func (e *Engine) lockCollection(dbName, colName string, write bool) (*db.Collection, func(), error) {
+	database, ok := e.Databases[dbName]
+	if !ok {
+		return nil, nil, fmt.Errorf("database %s %w", dbName, db.ErrNotFound)
+	}
+	database.RLock()
+	col, err := database.GetCollection(colName)
+	if err != nil {
+		database.RUnlock()
+		return nil, nil, err
+	}
+	if write {
+		col.Lock()
+	} else {
+		col.RLock()
+	}
+	return col, func() {
+		if write {
+			col.Unlock()
+		} else {
+			col.RUnlock()
+		}
+		database.RUnlock()
+	}, nil
+}
+
+// lockDocument resuelve db/colección/documento con e.mu ya tomado: deja la base
+// de datos y la colección en lectura y el documento en escritura si write (si
+// no, en lectura). unlock suelta los tres.
+func (e *Engine) lockDocument(dbName, colName, docName string, write bool) (*db.Document, func(), error) {
+	col, unlockCol, err := e.lockCollection(dbName, colName, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := col.GetDocument(docName)
+	if err != nil {
+		unlockCol()
+		return nil, nil, err
+	}
+	if write {
+		doc.Lock()
+	} else {
+		doc.RLock()
+	}
+	return doc, func() {
+		if write {
+			doc.Unlock()
+		} else {
+			doc.RUnlock()
+		}
+		unlockCol()
+	}, nil
+}
+
+// docView es un documento dentro del alcance de una lectura.
+type docView struct {
+	dbName     string
+	collection string
+	name       string
+	doc        *db.Document
+}
+
+// rlockScope toma en lectura la base de datos dbName, sus colecciones (las de
+// collections que existan o, si está vacío, todas) y todos sus documentos, en
+// orden de nombre, y los devuelve en ese orden. Mientras estén tomados nadie
+// escribe en ellos, así que lo que se capture de todos es un snapshot
+// consistente. El llamador debe tener e.mu tomado y llamar a unlock en cuanto
+// termine de capturar.
+func (e *Engine) rlockScope(dbName string, collections []string) ([]docView, func(), error) {
+	database, ok := e.Databases[dbName]
+	if !ok {
+		return nil, nil, fmt.Errorf("database %s %w", dbName, db.ErrNotFound)
+	}
+	database.RLock()
+	var held []func()
+	held = append(held, database.RUnlock)
+	unlock := func() {
+		for n := len(held) - 1; n >= 0; n-- {
+			held[n]()
+		}
+	}
+
+	var docs []docView
+	for _, colName := range sortedCollections(database, collections) {
+		col, ok := database.Collections[colName]
+		if !ok {
+			continue
+		}
+		col.RLock()
+		held = append(held, col.RUnlock)
+		for _, docName := range sortedDocuments(col) {
+			doc := col.Documents[docName]
+			doc.RLock()
+			held = append(held, doc.RUnlock)
+			docs = append(docs, docView{dbName: dbName, collection: colName, name: docName, doc: doc})
+		}
+	}
+	return docs, unlock, nil
+}
+
+// collect junta lo que pick devuelve de cada documento del alcance (versiones
+// actuales de los objetos, sin copiar) y suelta todos los locks antes de volver.
+func (e *Engine) collect(dbName string, collections []string, pick func(d docView) []*db.Object) ([]*db.Object, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	docs, unlock, err := e.rlockScope(dbName, collections)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var objs []*db.Object
+	for _, d := range docs {
+		objs = append(objs, pick(d)...)
+	}
+	return objs, nil
+}
+
+// sortedCollections devuelve collections sin repetidos o, si está vacío, todas
+// las de database, en orden. El llamador debe tener database tomada.
+func sortedCollections(database *db.Database, collections []string) []string {
+	var names []string
+	if len(collections) > 0 {
+		seen := make(map[string]bool, len(collections))
+		for _, name := range collections {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	} else {
+		for name := range database.Collections {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedDocuments devuelve los nombres de los documentos de col en orden. El
+// llamador debe tener col tomada.
+func sortedDocuments(col *db.Collection) []string {
+	names := make([]string, 0, len(col.Documents))
+	for name := range col.Documents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"fmt"
+	db "machDB/src/internal/db"
+	idx "machDB/src/internal/index"
+)
+
+// Tipos de join soportados por Join.
+const (
+	JoinInner = "inner"
+	JoinLeft  = "left"
+	JoinRight = "right"
+	JoinOuter = "outer"
+)
+
+// Join une leftCol.leftField con rightCol.rightField dentro de dbName. Cada fila
+// mezcla los campos de ambos objetos con el prefijo "coleccion." (y "coleccion.id"
+// con el ID del objeto); el lado sin pareja en left/right/outer no aporta campos.
+// En un self-join los prefijos son "coleccion_1." y "coleccion_2." para que un
+// lado no pise al otro.
+func (e *Engine) Join(kind, dbName, leftCol, leftField, rightCol, rightField string) ([]map[string]interface{}, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	database, ok := e.Databases[dbName]
+	if !ok {
+		return nil, fmt.Errorf("database %s %w", dbName, db.ErrNotFound)
+	}
+	database.RLock()
+	for _, c := range []string{leftCol, rightCol} {
+		if _, err := database.GetCollection(c); err != nil {
+			database.RUnlock()
+			return nil, err
+		}
+	}
+	database.RUnlock()
+
+	docs, unlock, err := e.rlockScope(dbName, []string{leftCol, rightCol})
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// El JoinIndex trabaja sobre un índice invertido con refs: se arma con los
+	// campos de join de los shards de los documentos de ambas colecciones.
+	index := make(idx.InvertedIndex)
+	objs := make(map[idx.ObjectRef]*db.Object)
+	left := idx.JoinSide{DB: dbName, Collection: leftCol, Field: leftField}
+	right := idx.JoinSide{DB: dbName, Collection: rightCol, Field: rightField}
+	fields := []string{leftField}
+	if rightField != leftField {
+		fields = append(fields, rightField)
+	}
+	for _, d := range docs {
+		for _, obj := range d.doc.Objects {
+			ref := idx.ObjectRef{DB: dbName, Collection: d.collection, Document: d.name, ID: obj.ID}
+			objs[ref] = obj
+			if d.collection == leftCol && (kind == JoinLeft || kind == JoinOuter || isIDField(leftField)) {
+				left.All = append(left.All, ref)
+			}
+			if d.collection == rightCol && (kind == JoinRight || kind == JoinOuter || isIDField(rightField)) {
+				right.All = append(right.All, ref)
+			}
+		}
+		for _, field := range fields {
+			for key, ids := range d.doc.Index().Inverted[field] {
+				if index[field] == nil {
+					index[field] = make(map[idx.Key][]idx.ObjectRef)
+				}
+				index[field][key] = append(index[field][key], d.refs(ids)...)
+			}
+		}
+	}
+
+	j := idx.NewJoinIndex(index)
+	var pairs []idx.JoinPair
+	switch kind {
+	case JoinInner:
+		pairs = j.JoinPropertiesInner(left, right)
+	case JoinLeft:
+		pairs = j.JoinPropertiesleft(left, right)
+	case JoinRight:
+		pairs = j.JoinPropertiesRight(left, right)
+	case JoinOuter:
+		pairs = j.JoinPropertiesOuter(left, right)
+	default:
+		return nil, fmt.Errorf("unknown join type %s", kind)
+	}
+
+	leftPrefix, rightPrefix := leftCol+".", rightCol+"."
+	if leftCol == rightCol {
+		leftPrefix, rightPrefix = leftCol+"_1.", rightCol+"_2."
+	}
+	rows := make([]map[string]interface{}, 0, len(pairs))
+	for _, p := range pairs {
+		row := make(map[string]interface{})
+		mergeRow(row, leftPrefix, p.Left, objs)
+		mergeRow(row, rightPrefix, p.Right, objs)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// mergeRow copia en row los campos del objeto de ref con prefix delante.
+func mergeRow(row map[string]interface{}, prefix string, ref *idx.ObjectRef, objs map[idx.ObjectRef]*db.Object) {
+	if ref == nil {
+		return
+	}
+	obj, ok := objs[*ref]
+	if !ok {
+		return
+	}
+	row[prefix+"id"] = obj.ID
+	for k, v := range obj.Fields {
+		row[prefix+k] = v
+	}
+}
+
+func isIDField(field string) bool {
+	return field == "id" || field == "_id"
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// joinEngine: shop/users/a con dos usuarios y shop/orders/o con tres pedidos,
+// uno de un usuario que no existe.
+func joinEngine(t *testing.T) *Engine {
+	return newTestEngine(t, "shop", docs{
+		"users/a": {
+			{"name": "ana", "boss": "bob"},
+			{"name": "bob"},
+		},
+		"orders/o": {
+			{"user": "ana", "uid": 0, "total": 10},
+			{"user": "ana", "uid": 0, "total": 5},
+			{"user": "zed", "uid": 9, "total": 1},
+		},
+	})
+}
+
+// rowSummary resume cada fila con los valores de fields ("<nil>" si falta) y
+// comprueba que el lado sin pareja no aporte campos.
+func rowSummary(t *testing.T, rows []map[string]interface{}, fields ...string) []string {
+	t.Helper()
+	var out []string
+	for _, row := range rows {
+		vals := make([]string, len(fields))
+		for n, f := range fields {
+			vals[n] = fmt.Sprint(row[f])
+		}
+		for k := range row {
+			side, _, _ := strings.Cut(k, ".")
+			if _, ok := row[side+".id"]; !ok {
+				t.Errorf("row %v has %s but no %s.id", row, k, side)
+			}
+		}
+		out = append(out, strings.Join(vals, " "))
+	}
+	return out
+}
+
+func TestJoin(t *testing.T) {
+	e := joinEngine(t)
+	tests := []struct {
+		kind          string
+		left, lfield  string
+		right, rfield string
+		want          []string
+	}{
+		{JoinInner, "users", "name", "orders", "user", []string{"ana 10", "ana 5"}},
+		{JoinLeft, "users", "name", "orders", "user", []string{"ana 10", "ana 5", "bob <nil>"}},
+		{JoinRight, "users", "name", "orders", "user", []string{"ana 10", "ana 5", "<nil> 1"}},
+		{JoinOuter, "users", "name", "orders", "user", []string{"ana 10", "ana 5", "bob <nil>", "<nil> 1"}},
+		{JoinInner, "users", "id", "orders", "uid", []string{"ana 10", "ana 5"}},
+		{JoinOuter, "users", "_id", "orders", "uid", []string{"ana 10", "ana 5", "bob <nil>", "<nil> 1"}},
+		{JoinRight, "orders", "uid", "users", "id", []string{"ana 10", "ana 5", "bob <nil>"}},
+	}
+	for _, tc := range tests {
+		name := fmt.Sprintf("%s %s.%s=%s.%s", tc.kind, tc.left, tc.lfield, tc.right, tc.rfield)
+		t.Run(name, func(t *testing.T) {
+			rows, err := e.Join(tc.kind, "shop", tc.left, tc.lfield, tc.right, tc.rfield)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rowSummary(t, rows, "users.name", "orders.total"); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("rows = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// En un self-join cada lado va con su propio prefijo: ninguno pisa al otro.
+func TestSelfJoin(t *testing.T) {
+	e := joinEngine(t)
+	rows, err := e.Join(JoinLeft, "shop", "users", "boss", "users", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		{"users_1.id": 0, "users_1.name": "ana", "users_1.boss": "bob", "users_2.id": 1, "users_2.name": "bob"},
+		{"users_1.id": 1, "users_1.name": "bob"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+}
+
+func TestJoinErrors(t *testing.T) {
+	e := joinEngine(t)
+	tests := []struct{ kind, dbName, left, right string }{
+		{"cross", "shop", "users", "orders"},
+		{JoinInner, "nope", "users", "orders"},
+		{JoinInner, "shop", "nope", "orders"},
+		{JoinInner, "shop", "users", "nope"},
+	}
+	for _, tc := range tests {
+		if _, err := e.Join(tc.kind, tc.dbName, tc.left, "name", tc.right, "user"); err == nil {
+			t.Errorf("Join(%s, %s, %s, %s): want error", tc.kind, tc.dbName, tc.left, tc.right)
+		}
+	}
+}
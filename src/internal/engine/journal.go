@@ -0,0 +1,171 @@
+package engine
+
+import "fmt"
+
+// Operaciones que se registran en el journal.
+const (
+	MutCreateDatabase   = "create_db"
+	MutCreateCollection = "create_collection"
+	MutCreateDocument   = "create_document"
+	MutInsert           = "insert"
+	MutMerge            = "merge"
+	MutModify           = "modify"
+	MutDeleteDatabase   = "delete_db"
+	MutDeleteCollection = "delete_collection"
+	MutDeleteDocument   = "delete_document"
+	MutSetKeys          = "set_keys"
+	// MutTx agrupa en Mutations las de una transacción confirmada, que se
+	// reproducen todas o ninguna.
+	MutTx = "tx"
+)
+
+// Mutation describe un cambio sobre el engine con lo necesario para repetirlo.
+type Mutation struct {
+	Op         string                   `json:"op"`
+	DB         string                   `json:"db"`
+	Collection string                   `json:"collection,omitempty"`
+	Document   string                   `json:"document,omitempty"`
+	Objects    []map[string]interface{} `json:"objects,omitempty"`
+	Filter     map[string]interface{}   `json:"filter,omitempty"`
+	Fields     map[string]interface{}   `json:"fields,omitempty"`
+	Keys       string                   `json:"keys,omitempty"`
+	Mutations  []Mutation               `json:"mutations,omitempty"`
+	// LSN es el número de secuencia que le da el journal (0 si no numera).
+	LSN uint64 `json:"lsn,omitempty"`
+}
+
+// Journal recibe cada mutación ya validada y antes de aplicarla (write-ahead).
+// Si Record falla la mutación no se aplica.
+type Journal interface {
+	Record(m Mutation) error
+}
+
+// SetJournal engancha j (nil lo quita). Se llama después de reproducir el log,
+// para que Apply no vuelva a registrar lo que ya estaba en él.
+func (e *Engine) SetJournal(j Journal) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.journal = j
+}
+
+// record pasa m al journal si hay uno. El llamador debe tener e.mu tomado.
+func (e *Engine) record(m Mutation) error {
+	if e.journal == nil {
+		return nil
+	}
+	return e.journal.Record(m)
+}
+
+// Apply vuelve a ejecutar una mutación registrada.
+func (e *Engine) Apply(m Mutation) error {
+	switch m.Op {
+	case MutCreateDatabase, MutDeleteDatabase, MutTx:
+		e.mu.Lock()
+		defer e.mu.Unlock()
+	default:
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+	}
+	_, err := e.applyLocked(m)
+	return err
+}
+
+// applyLocked ejecuta m con e.mu ya tomado (en escritura para create_db,
+// delete_db y tx), igual que el método público correspondiente (los *Locked son
+// esos métodos sin tomar e.mu). Devuelve los IDs insertados o afectados cuando
+// la operación los tiene.
+func (e *Engine) applyLocked(m Mutation) ([]int, error) {
+	switch m.Op {
+	case MutCreateDatabase:
+		return nil, e.createDatabaseLocked(m.DB)
+	case MutCreateCollection:
+		return nil, e.createCollectionLocked(m.DB, m.Collection)
+	case MutCreateDocument:
+		return nil, e.createDocumentLocked(m.DB, m.Collection, m.Document)
+	case MutInsert:
+		return e.insertObjectsLocked(m.DB, m.Collection, m.Document, m.Objects)
+	case MutMerge:
+		return e.mergeObjectsLocked(m.DB, m.Collection, m.Document, m.Filter, m.Fields)
+	case MutModify:
+		return e.applyUpdates(m)
+	case MutDeleteDatabase:
+		return nil, e.deleteDatabaseLocked(m.DB)
+	case MutDeleteCollection:
+		return nil, e.deleteCollectionLocked(m.DB, m.Collection)
+	case MutDeleteDocument:
+		return nil, e.deleteDocumentLocked(m.DB, m.Collection, m.Document)
+	case MutSetKeys:
+		return nil, e.setCollectionKeysLocked(m.DB, m.Collection, m.Keys)
+	case MutTx:
+		return nil, e.commitLocked(m.Mutations)
+	default:
+		return nil, fmt.Errorf("unknown mutation %s", m.Op)
+	}
+}
+
+// Replay es Apply para reproducir un journal sobre un snapshot que puede tener ya
+// parte de sus mutaciones (un crash entre escribir el snapshot y vaciar el log,
+// o a mitad de escribirlo). Se salta lo que el snapshot ya contiene:
+//
+//   - insert, merge y modify con LSN no mayor que el db.Document.LSN del documento
+//   - crear algo que ya existe y borrar algo que ya no existe
+//
+// Borrar algo que existe siempre se repite: si el snapshot ya tenía la versión
+// recreada después, el log tiene toda su historia (se creó después del borrado)
+// y se vuelve a construir. Las mutaciones de una transacción se tratan una a una
+// con el LSN de la transacción. Sin LSN (logs anteriores) es igual que Apply.
+func (e *Engine) Replay(m Mutation) error {
+	if m.LSN == 0 {
+		return e.Apply(m)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	muts := []Mutation{m}
+	if m.Op == MutTx {
+		muts = m.Mutations
+	}
+	for _, sub := range muts {
+		if e.inSnapshotLocked(sub, m.LSN) {
+			continue
+		}
+		if _, err := e.applyLocked(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inSnapshotLocked dice si el estado actual ya incluye m, registrada con lsn (ver
+// Replay). Si m no se puede resolver devuelve false para que applyLocked dé el
+// error. El llamador debe tener e.mu tomado en escritura.
+func (e *Engine) inSnapshotLocked(m Mutation, lsn uint64) bool {
+	switch m.Op {
+	case MutCreateDatabase, MutDeleteDatabase:
+		_, exists := e.Databases[m.DB]
+		return exists == (m.Op == MutCreateDatabase)
+	case MutCreateCollection, MutDeleteCollection:
+		database, ok := e.Databases[m.DB]
+		if !ok {
+			return false
+		}
+		_, err := database.GetCollection(m.Collection)
+		return (err == nil) == (m.Op == MutCreateCollection)
+	case MutCreateDocument, MutDeleteDocument:
+		col, unlock, err := e.lockCollection(m.DB, m.Collection, false)
+		if err != nil {
+			return false
+		}
+		defer unlock()
+		_, err = col.GetDocument(m.Document)
+		return (err == nil) == (m.Op == MutCreateDocument)
+	case MutInsert, MutMerge, MutModify:
+		doc, unlock, err := e.lockDocument(m.DB, m.Collection, m.Document, false)
+		if err != nil {
+			return false
+		}
+		defer unlock()
+		return lsn <= doc.LSN
+	}
+	return false
+}
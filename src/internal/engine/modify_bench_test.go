@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+)
+
+// BenchmarkModifyByID modifica un objeto por id en documentos de distinto tamaño:
+// se resuelve con las posiciones por ID y solo se sustituye su posición, así que
+// el tiempo apenas depende de objects.
+//
+//	go test ./src/internal/engine -run '^$' -bench ModifyByID
+func BenchmarkModifyByID(b *testing.B) {
+	for _, objects := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("objects=%d", objects), func(b *testing.B) {
+			e := benchEngine(b, 1, 1, 1)
+			objs := make([]map[string]interface{}, objects)
+			for n := range objs {
+				objs[n] = map[string]interface{}{"n": n}
+			}
+			if _, err := e.InsertObjects("db0", "c0", "d0", objs); err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				filter := map[string]interface{}{"id": n % objects}
+				if _, err := e.ModifyObjects("db0", "c0", "d0", filter, map[string]interface{}{"seen": n}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
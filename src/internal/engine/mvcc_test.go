@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	db "machDB/src/internal/db"
+)
+
+// fieldValues resume objs como "id:v" en orden.
+func fieldValues(objs []*db.Object, field string) []string {
+	out := make([]string, len(objs))
+	for n, obj := range objs {
+		out[n] = fmt.Sprintf("%d:%v", obj.ID, obj.Fields[field])
+	}
+	return out
+}
+
+// Una lectura que ya tomó su snapshot devuelve las versiones de ese momento
+// aunque antes de copiarlas otro modifique e inserte en el mismo documento.
+func TestReadSeesSnapshot(t *testing.T) {
+	tests := []struct {
+		name string
+		read func(e *Engine) ([]*db.Object, error)
+	}{
+		{"ListObjects", func(e *Engine) ([]*db.Object, error) {
+			return e.ListObjects("shop", "users", "a")
+		}},
+		{"Find", func(e *Engine) ([]*db.Object, error) {
+			return e.Find("v", 1, "shop")
+		}},
+		{"FindByQuery", func(e *Engine) ([]*db.Object, error) {
+			return e.FindByQuery("v:1", "shop", "users")
+		}},
+		{"FindByQueries", func(e *Engine) ([]*db.Object, error) {
+			return e.FindByQueries([]string{"v>=1", "v<2"}, "shop")
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEngine(t, "shop", docs{"users/a": {{"v": 1}, {"v": 1}}})
+			testHookSnapshot = func() {
+				testHookSnapshot = nil
+				if _, err := e.ModifyObjects("shop", "users", "a", map[string]interface{}{"id": 0}, map[string]interface{}{"v": 2}); err != nil {
+					t.Error(err)
+				}
+				if _, err := e.InsertObjects("shop", "users", "a", []map[string]interface{}{{"v": 1}}); err != nil {
+					t.Error(err)
+				}
+			}
+			defer func() { testHookSnapshot = nil }()
+
+			objs, err := tc.read(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if testHookSnapshot != nil {
+				t.Fatal("the read never reached the hook")
+			}
+			if got, want := fieldValues(objs, "v"), []string{"0:1", "1:1"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("read = %v, want the versions from its start %v", got, want)
+			}
+			objs, _ = e.ListObjects("shop", "users", "a")
+			if got, want := fieldValues(objs, "v"), []string{"0:2", "1:1", "2:1"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("after the read = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// Lectores y escritores a la vez sobre el mismo documento (pensado para -race):
+// cada versión cambia a y b juntos, así que ninguna lectura puede verlos
+// distintos.
+func TestConcurrentReadsAndWrites(t *testing.T) {
+	e := newTestEngine(t, "shop", docs{"users/a": {{"a": 0, "b": 0}, {"a": 0, "b": 0}}})
+	const rounds = 200
+	check := func(objs []*db.Object, err error) {
+		if err != nil && !errors.Is(err, ErrNoResults) {
+			t.Error(err)
+			return
+		}
+		for _, obj := range objs {
+			if obj.Fields["a"] != obj.Fields["b"] {
+				t.Errorf("object %d mixes versions: %v", obj.ID, obj.Fields)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		for n := 1; n <= rounds; n++ {
+			if _, err := e.ModifyObjects("shop", "users", "a", nil, map[string]interface{}{"a": n, "b": n}); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for n := 1; n <= rounds; n++ {
+			if _, err := e.InsertObjects("shop", "users", "a", []map[string]interface{}{{"a": -n, "b": -n}}); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for n := 0; n < rounds; n++ {
+			check(e.ListObjects("shop", "users", "a"))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for n := 0; n < rounds; n++ {
+			check(e.FindByQuery("a>=0", "shop"))
+		}
+	}()
+	wg.Wait()
+
+	objs, err := e.ListObjects("shop", "users", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 2+rounds {
+		t.Errorf("%d objects, want %d", len(objs), 2+rounds)
+	}
+}
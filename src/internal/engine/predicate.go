@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"fmt"
+	db "machDB/src/internal/db"
+	idx "machDB/src/internal/index"
+	"strings"
+)
+
+// Operadores soportados en una consulta de find.
+const (
+	OpEq      = ":"
+	OpLt      = "<"
+	OpLte     = "<="
+	OpGt      = ">"
+	OpGte     = ">="
+	OpBetween = ".." // campo:lo..hi, ambos extremos incluidos
+)
+
+// Predicate es una condición simple sobre un campo, resoluble con los índices.
+type Predicate struct {
+	Field string
+	Op    string
+	Value idx.Key
+	Upper idx.Key // solo para OpBetween
+}
+
+// ParsePredicate interpreta "campo:valor", "campo>valor", "campo>=valor",
+// "campo<valor", "campo<=valor" y "campo:lo..hi".
+func ParsePredicate(query string) (Predicate, error) {
+	pos := strings.IndexAny(query, ":<>")
+	if pos <= 0 {
+		return Predicate{}, fmt.Errorf("%w: expected field:value, field<value or field:lo..hi", ErrBadQuery)
+	}
+	p := Predicate{Field: strings.TrimSpace(query[:pos])}
+	rest := query[pos:]
+
+	switch {
+	case strings.HasPrefix(rest, "<="):
+		p.Op, rest = OpLte, rest[2:]
+	case strings.HasPrefix(rest, ">="):
+		p.Op, rest = OpGte, rest[2:]
+	case strings.HasPrefix(rest, "<"):
+		p.Op, rest = OpLt, rest[1:]
+	case strings.HasPrefix(rest, ">"):
+		p.Op, rest = OpGt, rest[1:]
+	default:
+		p.Op, rest = OpEq, rest[1:]
+	}
+
+	if p.Op == OpEq && !isQuoted(rest) {
+		if lo, hi, ok := strings.Cut(rest, OpBetween); ok {
+			p.Op = OpBetween
+			p.Value = idx.KeyFromText(lo)
+			p.Upper = idx.KeyFromText(hi)
+			if p.Value.Type != p.Upper.Type {
+				return Predicate{}, fmt.Errorf("%w: between bounds of different types", ErrBadQuery)
+			}
+			return p, nil
+		}
+	}
+	p.Value = idx.KeyFromText(rest)
+	return p, nil
+}
+
+func isQuoted(s string) bool {
+	s = strings.TrimSpace(s)
+	return len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]
+}
+
+// lookup devuelve los IDs de los objetos de doc que cumplen p, usando su shard
+// del índice. Igualdad va al índice invertido; los rangos sacan las claves del
+// ordenado. El llamador debe tener doc tomado.
+func lookup(doc *db.Document, p Predicate) []int {
+	if p.Op == OpEq {
+		return doc.Index().Lookup(p.Field, p.Value)
+	}
+
+	var lo, hi *idx.Bound
+	switch p.Op {
+	case OpLt:
+		hi = &idx.Bound{Key: p.Value}
+	case OpLte:
+		hi = &idx.Bound{Key: p.Value, Inclusive: true}
+	case OpGt:
+		lo = &idx.Bound{Key: p.Value}
+	case OpGte:
+		lo = &idx.Bound{Key: p.Value, Inclusive: true}
+	case OpBetween:
+		lo = &idx.Bound{Key: p.Value, Inclusive: true}
+		hi = &idx.Bound{Key: p.Upper, Inclusive: true}
+	}
+	return doc.Index().Range(p.Field, lo, hi)
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// rangeEngine: shop/people con edades numéricas, una edad en texto y un objeto
+// sin edad, repartidos en dos documentos.
+func rangeEngine(t *testing.T) *Engine {
+	return newTestEngine(t, "shop", docs{
+		"people/a": {{"name": "ana", "age": 17}, {"name": "bob", "age": 18}, {"name": "cid", "age": 30.5}},
+		"people/b": {{"name": "dan", "age": 45}, {"name": "eva", "age": "40"}, {"name": "fay"}},
+	})
+}
+
+func TestFindByQueryRange(t *testing.T) {
+	e := rangeEngine(t)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"age>18", []string{"cid", "dan"}},
+		{"age>=18", []string{"bob", "cid", "dan"}},
+		{"age<18", []string{"ana"}},
+		{"age<=18", []string{"ana", "bob"}},
+		{"age:18..45", []string{"bob", "cid", "dan"}},
+		{"age:30.5", []string{"cid"}},
+		{"age:'40'", []string{"eva"}},
+		{"age>100", nil},
+		{"name<c", []string{"ana", "bob"}},
+		{"name:b..d", []string{"bob", "cid"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			objs, err := e.FindByQuery(tc.query, "shop")
+			if tc.want == nil {
+				if !errors.Is(err, ErrNoResults) {
+					t.Fatalf("FindByQuery = %v, %v; want ErrNoResults", objs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, obj := range objs {
+				names = append(names, obj.Fields["name"].(string))
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tc.want) {
+				t.Errorf("FindByQuery = %v, want %v", names, tc.want)
+			}
+		})
+	}
+}
+
+// El índice ordenado sigue a los cambios de valor de un campo.
+func TestFindByQueryRangeAfterModify(t *testing.T) {
+	e := rangeEngine(t)
+	if _, err := e.ModifyObjects("shop", "people", "a", map[string]interface{}{"name": "ana"}, map[string]interface{}{"age": 50}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.ModifyObjects("shop", "people", "b", map[string]interface{}{"name": "dan"}, map[string]interface{}{"age": nil}); err != nil {
+		t.Fatal(err)
+	}
+	objs, err := e.FindByQuery("age>=45", "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 1 || objs[0].Fields["name"] != "ana" {
+		t.Errorf("FindByQuery(age>=45) = %v, want only ana", objs)
+	}
+}
+
+func TestParsePredicateErrors(t *testing.T) {
+	for _, query := range []string{"age", ":18", "age:1..b"} {
+		if _, err := ParsePredicate(query); !errors.Is(err, ErrBadQuery) {
+			t.Errorf("ParsePredicate(%q) = %v, want ErrBadQuery", query, err)
+		}
+	}
+}
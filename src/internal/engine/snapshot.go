@@ -0,0 +1,91 @@
+package engine
+
+import (
+	db "machDB/src/internal/db"
+	idx "machDB/src/internal/index"
+)
+
+// Replace sustituye todas las bases de datos (por ejemplo al cargar desde disco)
+// y reconstruye los shards del índice de cada documento.
+func (e *Engine) Replace(dbs map[string]*db.Database) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.Databases = dbs
+	for _, database := range dbs {
+		for _, col := range database.Collections {
+			for _, doc := range col.Documents {
+				doc.Reindex()
+			}
+		}
+	}
+}
+
+// Snapshot devuelve una copia de las bases de datos dbNames (todas si no se
+// indica ninguna) para leerla sin locks, por ejemplo al volcarla a disco:
+// estructuras propias con los slices de objetos copiados, que apuntan a las
+// mismas versiones de Object (que no cambian). Solo la copia de punteros se hace
+// con e.mu en escritura, que excluye a lectores y escritores; lo que se haga
+// después con ella ya no los bloquea. at, si no es nil, se llama en ese mismo
+// instante (por ejemplo para leer hasta dónde llega el journal).
+func (e *Engine) Snapshot(at func(), dbNames ...string) map[string]*db.Database {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(dbNames) == 0 {
+		for name := range e.Databases {
+			dbNames = append(dbNames, name)
+		}
+	}
+	out := make(map[string]*db.Database, len(dbNames))
+	for _, name := range dbNames {
+		database, ok := e.Databases[name]
+		if !ok {
+			continue
+		}
+		cp := database.Copy()
+		for colName, col := range cp.Collections {
+			colCp := col.Copy()
+			for docName, doc := range colCp.Documents {
+				colCp.Documents[docName] = doc.Copy()
+			}
+			cp.Collections[colName] = colCp
+		}
+		out[name] = cp
+	}
+	if at != nil {
+		at()
+	}
+	return out
+}
+
+// IndexedFields devuelve, por campo indexado, cuántos valores distintos tiene.
+func (e *Engine) IndexedFields() map[string]int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	keys := make(map[string]map[idx.Key]bool)
+	for dbName := range e.Databases {
+		docs, unlock, err := e.rlockScope(dbName, nil)
+		if err != nil {
+			continue
+		}
+		for _, d := range docs {
+			for field, valMap := range d.doc.Index().Inverted {
+				if keys[field] == nil {
+					keys[field] = make(map[idx.Key]bool)
+				}
+				for key := range valMap {
+					keys[field][key] = true
+				}
+			}
+		}
+		unlock()
+	}
+
+	fields := make(map[string]int, len(keys))
+	for field, set := range keys {
+		fields[field] = len(set)
+	}
+	return fields
+}
@@ -0,0 +1,478 @@
+package engine
+
+import (
+	"fmt"
+	db "machDB/src/internal/db"
+	"sort"
+)
+
+// Tx agrupa mutaciones que se aplican todas juntas en Commit, o ninguna si se
+// llama a Rollback. Cada operación se valida en el momento contra una vista
+// privada (un overlay) con copias solo de lo que toca, así que el error (DB
+// inexistente, filtro sin resultados en un merge...) aparece en la operación que
+// lo causa y los IDs que devuelve InsertObjects son los que tendrán los objetos
+// tras el commit, salvo que otro escritor cambie esos documentos entretanto.
+//
+// Las lecturas del engine (Find, ListObjects...) no ven lo pendiente hasta el
+// commit. Una Tx no es segura para usarse desde varias goroutines.
+type Tx struct {
+	e    *Engine
+	view *overlay
+	muts []Mutation
+	done bool
+}
+
+// Begin abre una transacción sobre e.
+func (e *Engine) Begin() *Tx {
+	return &Tx{e: e, view: newOverlay()}
+}
+
+// Len devuelve cuántas operaciones tiene pendientes la transacción.
+func (tx *Tx) Len() int {
+	return len(tx.muts)
+}
+
+func (tx *Tx) CreateDatabase(name string) error {
+	_, err := tx.exec(Mutation{Op: MutCreateDatabase, DB: name})
+	return err
+}
+
+func (tx *Tx) CreateCollection(dbName, colName string) error {
+	_, err := tx.exec(Mutation{Op: MutCreateCollection, DB: dbName, Collection: colName})
+	return err
+}
+
+func (tx *Tx) SetCollectionKeys(dbName, colName, keys string) error {
+	_, err := tx.exec(Mutation{Op: MutSetKeys, DB: dbName, Collection: colName, Keys: keys})
+	return err
+}
+
+func (tx *Tx) CreateDocument(dbName, colName, docName string) error {
+	_, err := tx.exec(Mutation{Op: MutCreateDocument, DB: dbName, Collection: colName, Document: docName})
+	return err
+}
+
+func (tx *Tx) InsertObjects(dbName, colName, docName string, objs []map[string]interface{}) ([]int, error) {
+	return tx.exec(Mutation{Op: MutInsert, DB: dbName, Collection: colName, Document: docName, Objects: objs})
+}
+
+func (tx *Tx) MergeObjects(dbName, colName, docName string, filter, fields map[string]interface{}) ([]int, error) {
+	return tx.exec(Mutation{Op: MutMerge, DB: dbName, Collection: colName, Document: docName, Filter: filter, Fields: fields})
+}
+
+func (tx *Tx) ModifyObjects(dbName, colName, docName string, filter, updates map[string]interface{}) (int, error) {
+	ids, err := tx.exec(Mutation{Op: MutModify, DB: dbName, Collection: colName, Document: docName, Filter: filter, Fields: updates})
+	return len(ids), err
+}
+
+func (tx *Tx) DeleteDatabase(dbName string) error {
+	_, err := tx.exec(Mutation{Op: MutDeleteDatabase, DB: dbName})
+	return err
+}
+
+func (tx *Tx) DeleteCollection(dbName, colName string) error {
+	_, err := tx.exec(Mutation{Op: MutDeleteCollection, DB: dbName, Collection: colName})
+	return err
+}
+
+func (tx *Tx) DeleteDocument(dbName, colName, docName string) error {
+	_, err := tx.exec(Mutation{Op: MutDeleteDocument, DB: dbName, Collection: colName, Document: docName})
+	return err
+}
+
+// ListDatabases ve lo pendiente: para las bases de datos que ha tocado la
+// transacción usa el overlay y para el resto el engine.
+func (tx *Tx) ListDatabases() []string {
+	var names []string
+	for _, name := range tx.e.ListDatabases() {
+		if !tx.view.staged[name] {
+			names = append(names, name)
+		}
+	}
+	for name := range tx.view.staged {
+		if _, ok := tx.view.Databases[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ListCollections es Engine.ListCollections viendo lo pendiente.
+func (tx *Tx) ListCollections(dbName string) ([]string, error) {
+	if tx.view.staged[dbName] {
+		return tx.view.ListCollections(dbName)
+	}
+	return tx.e.ListCollections(dbName)
+}
+
+// ListDocuments es Engine.ListDocuments viendo lo pendiente.
+func (tx *Tx) ListDocuments(dbName, colName string) ([]string, error) {
+	if tx.view.staged[dbName] {
+		return tx.view.ListDocuments(dbName, colName)
+	}
+	return tx.e.ListDocuments(dbName, colName)
+}
+
+// exec aplica m al overlay y, si sale bien, la deja pendiente. Lo que queda
+// pendiente es una copia de m tal como se aplicó (con las claves que se hayan
+// generado al insertar): ni el llamador ni el overlay pueden cambiar lo que se
+// aplicará en el commit.
+func (tx *Tx) exec(m Mutation) ([]int, error) {
+	if tx.done {
+		return nil, ErrTxDone
+	}
+	m = copyMutation(m)
+	unlock := tx.e.lockScope([]Mutation{m}, false)
+	tx.view.stage(tx.e, m)
+	unlock()
+
+	ids, err := tx.view.applyLocked(m)
+	if err != nil {
+		return nil, err
+	}
+	tx.muts = append(tx.muts, copyMutation(m))
+	return ids, nil
+}
+
+// Commit aplica las operaciones pendientes de forma atómica: se vuelven a
+// validar contra el estado actual y, si alguna falla, no se aplica ninguna y la
+// transacción queda terminada igualmente. En el journal queda un único MutTx.
+//
+// Mientras valida y aplica solo tiene en escritura lo que cambian las
+// operaciones (ver lockScope): el resto del engine sigue disponible.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	if len(tx.muts) == 0 {
+		return nil
+	}
+	unlock := tx.e.lockScope(tx.muts, true)
+	defer unlock()
+	return tx.e.commitLocked(tx.muts)
+}
+
+// Truncate descarta las operaciones pendientes a partir de la n-ésima: con Len
+// hace de savepoint. El overlay se rehace con las n primeras contra el estado
+// actual del engine; si alguna ya no se puede aplicar (otro escritor cambió lo
+// que tocaba) el commit fallaría igual, así que la transacción se da por
+// terminada y se devuelve el error.
+func (tx *Tx) Truncate(n int) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	if n < 0 || n > len(tx.muts) {
+		return fmt.Errorf("truncate to %d: transaction has %d operations", n, len(tx.muts))
+	}
+	if n == len(tx.muts) {
+		return nil
+	}
+	muts := tx.muts[:n]
+	tx.view, tx.muts = newOverlay(), nil
+	for k, m := range muts {
+		if _, err := tx.exec(m); err != nil {
+			tx.done, tx.muts = true, nil
+			return fmt.Errorf("transaction aborted at operation %d (%s): %w", k+1, m.Op, err)
+		}
+	}
+	return nil
+}
+
+// Rollback descarta las operaciones pendientes.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.muts = nil
+	return nil
+}
+
+// commitLocked valida muts sobre un overlay nuevo (así ve lo que otros
+// escritores hayan cambiado desde que se prepararon), las registra como un solo
+// MutTx y lleva el resultado al engine. Lo que se registra son las copias
+// validadas, que ya traen las claves que falten (si entretanto otro escritor dio
+// claves a una colección). El llamador debe tener los locks de
+// lockScope(muts, true) o e.mu en escritura.
+func (e *Engine) commitLocked(muts []Mutation) error {
+	o := newOverlay()
+	checked := make([]Mutation, len(muts))
+	for n, m := range muts {
+		o.stage(e, m)
+		checked[n] = copyMutation(m)
+		if _, err := o.applyLocked(checked[n]); err != nil {
+			return fmt.Errorf("transaction aborted at operation %d (%s): %w", n+1, m.Op, err)
+		}
+	}
+
+	if err := e.record(Mutation{Op: MutTx, Mutations: checked}); err != nil {
+		return err
+	}
+	o.install(e)
+	return nil
+}
+
+// overlay es un engine privado con copias de lo que tocan unas mutaciones,
+// hechas la primera vez que hacen falta: de las bases de datos y colecciones una
+// copia superficial (sus mapas, que siguen apuntando a lo que no se toca) y de
+// los documentos cuyos objetos cambian una copia completa. Aplicar mutaciones
+// sobre él no toca el engine original, y el coste es el de lo tocado, no el de
+// la base de datos entera.
+type overlay struct {
+	*Engine
+	staged map[string]bool      // bases de datos ya copiadas (o que no existían)
+	copies map[interface{}]bool // structs del overlay que son copia de uno del engine
+}
+
+func newOverlay() *overlay {
+	return &overlay{Engine: NewEngine(), staged: make(map[string]bool), copies: make(map[interface{}]bool)}
+}
+
+// stage copia de e a o lo que falte para aplicar m sobre o. El llamador debe
+// tener tomados (al menos en lectura) los locks de e que cubren m (ver
+// lockScope).
+func (o *overlay) stage(e *Engine, m Mutation) {
+	live, inLive := e.Databases[m.DB]
+	if !o.staged[m.DB] {
+		o.staged[m.DB] = true
+		if inLive {
+			cp := live.Copy()
+			o.Databases[m.DB] = cp
+			o.copies[cp] = true
+		}
+	}
+	database, ok := o.Databases[m.DB]
+	if m.Collection == "" || !ok || !inLive {
+		return
+	}
+
+	col, ok := database.Collections[m.Collection]
+	if !ok {
+		return
+	}
+	liveCol := live.Collections[m.Collection]
+	if col == liveCol {
+		col = liveCol.Copy()
+		database.Collections[m.Collection] = col
+		o.copies[col] = true
+	}
+	if m.Document == "" || !writesObjects(m.Op) || liveCol == nil {
+		return
+	}
+
+	doc, ok := col.Documents[m.Document]
+	if ok && doc == liveCol.Documents[m.Document] {
+		cp := doc.Clone()
+		col.Documents[m.Document] = cp
+		o.copies[cp] = true
+	}
+}
+
+// install lleva a e lo que cambió en o: los documentos copiados se adoptan en
+// su sitio y lo creado o borrado se añade o quita de los mapas. El llamador debe
+// tener los locks de escritura que pidió lockScope para las mismas mutaciones.
+func (o *overlay) install(e *Engine) {
+	for name := range o.staged {
+		database, ok := o.Databases[name]
+		live, inLive := e.Databases[name]
+		switch {
+		case !ok:
+			delete(e.Databases, name)
+		case !inLive || !o.copies[database]:
+			e.Databases[name] = database
+		default:
+			o.installCollections(live, database)
+		}
+	}
+}
+
+func (o *overlay) installCollections(live, database *db.Database) {
+	for name, col := range database.Collections {
+		liveCol, ok := live.Collections[name]
+		switch {
+		case col == liveCol:
+		case ok && o.copies[col]:
+			o.installDocuments(liveCol, col)
+		default:
+			live.Collections[name] = col
+		}
+	}
+	for name := range live.Collections {
+		if _, ok := database.Collections[name]; !ok {
+			delete(live.Collections, name)
+		}
+	}
+}
+
+func (o *overlay) installDocuments(live, col *db.Collection) {
+	if live.Keys != col.Keys {
+		live.Keys = col.Keys
+	}
+	for name, doc := range col.Documents {
+		liveDoc, ok := live.Documents[name]
+		switch {
+		case doc == liveDoc:
+		case ok && o.copies[doc]:
+			liveDoc.Adopt(doc)
+		default:
+			live.Documents[name] = doc
+		}
+	}
+	for name := range live.Documents {
+		if _, ok := col.Documents[name]; !ok {
+			delete(live.Documents, name)
+		}
+	}
+}
+
+// writesObjects indica si op cambia los objetos de un documento.
+func writesObjects(op string) bool {
+	return op == MutInsert || op == MutMerge || op == MutModify
+}
+
+// lockScope toma los locks de e que cubren muts, de arriba abajo y en orden de
+// nombre. Con write, en escritura el nivel que cambia cada mutación (e.mu para
+// crear o borrar bases de datos, la base de datos para colecciones y claves, la
+// colección para documentos y el documento para sus objetos) y en lectura los de
+// encima; sin write, todos en lectura. Bajo un lock de escritura no se toma
+// nada más. Devuelve la función que los suelta.
+func (e *Engine) lockScope(muts []Mutation, write bool) func() {
+	type colScope struct {
+		write bool
+		docs  map[string]bool
+	}
+	type dbScope struct {
+		write bool
+		cols  map[string]*colScope
+	}
+	global := false
+	scope := make(map[string]*dbScope)
+	for _, m := range muts {
+		d, ok := scope[m.DB]
+		if !ok {
+			d = &dbScope{cols: make(map[string]*colScope)}
+			scope[m.DB] = d
+		}
+		switch m.Op {
+		case MutCreateDatabase, MutDeleteDatabase:
+			global = true
+			continue
+		case MutCreateCollection, MutDeleteCollection, MutSetKeys:
+			d.write = true
+		}
+		// La colección se toma aunque cambie en el nivel de arriba: sin write
+		// hace falta su lock de lectura para copiarla
+		c, ok := d.cols[m.Collection]
+		if !ok {
+			c = &colScope{docs: make(map[string]bool)}
+			d.cols[m.Collection] = c
+		}
+		switch {
+		case writesObjects(m.Op):
+			c.docs[m.Document] = true
+		case m.Op == MutCreateDocument || m.Op == MutDeleteDocument:
+			c.write = true
+		}
+	}
+
+	var held []func()
+	unlock := func() {
+		for n := len(held) - 1; n >= 0; n-- {
+			held[n]()
+		}
+	}
+	if write && global {
+		e.mu.Lock()
+		held = append(held, e.mu.Unlock)
+		return unlock
+	}
+	e.mu.RLock()
+	held = append(held, e.mu.RUnlock)
+
+	for _, dbName := range sortedKeys(scope) {
+		d := scope[dbName]
+		database, ok := e.Databases[dbName]
+		if !ok {
+			continue
+		}
+		if write && d.write {
+			database.Lock()
+			held = append(held, database.Unlock)
+			continue
+		}
+		database.RLock()
+		held = append(held, database.RUnlock)
+
+		for _, colName := range sortedKeys(d.cols) {
+			c := d.cols[colName]
+			col, ok := database.Collections[colName]
+			if !ok {
+				continue
+			}
+			if write && c.write {
+				col.Lock()
+				held = append(held, col.Unlock)
+				continue
+			}
+			col.RLock()
+			held = append(held, col.RUnlock)
+
+			for _, docName := range sortedKeys(c.docs) {
+				doc, ok := col.Documents[docName]
+				if !ok {
+					continue
+				}
+				if write {
+					doc.Lock()
+					held = append(held, doc.Unlock)
+				} else {
+					doc.RLock()
+					held = append(held, doc.RUnlock)
+				}
+			}
+		}
+	}
+	return unlock
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// copyMutation copia los mapas de m (los valores anidados se comparten).
+func copyMutation(m Mutation) Mutation {
+	if m.Objects != nil {
+		objs := make([]map[string]interface{}, len(m.Objects))
+		for n, obj := range m.Objects {
+			objs[n] = copyFields(obj)
+		}
+		m.Objects = objs
+	}
+	m.Filter = copyFields(m.Filter)
+	m.Fields = copyFields(m.Fields)
+	if m.Mutations != nil {
+		muts := make([]Mutation, len(m.Mutations))
+		for n, sub := range m.Mutations {
+			muts[n] = copyMutation(sub)
+		}
+		m.Mutations = muts
+	}
+	return m
+}
+
+func copyFields(fields map[string]interface{}) map[string]interface{} {
+	if fields == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		out[k] = v
+	}
+	return out
+}
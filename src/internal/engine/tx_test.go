@@ -0,0 +1,276 @@
+package engine
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	db "machDB/src/internal/db"
+	idx "machDB/src/internal/index"
+)
+
+// memJournal guarda en memoria lo que se registra.
+type memJournal struct {
+	muts []Mutation
+}
+
+func (j *memJournal) Record(m Mutation) error {
+	j.muts = append(j.muts, copyMutation(m))
+	return nil
+}
+
+// txEngine: shop/users/{a,b} y shop/orders/o, con un objeto en cada documento.
+func txEngine(t *testing.T) *Engine {
+	return newTestEngine(t, "shop", docs{
+		"users/a":  {{"name": "a"}},
+		"users/b":  {{"name": "b"}},
+		"orders/o": {{"user": "a"}},
+	})
+}
+
+// state resume el engine como db/colección/documento -> campos "name" y "user".
+func state(t *testing.T, e *Engine) map[string][]string {
+	t.Helper()
+	out := make(map[string][]string)
+	for _, dbName := range e.ListDatabases() {
+		cols, _ := e.ListCollections(dbName)
+		for _, col := range cols {
+			docs, _ := e.ListDocuments(dbName, col)
+			for _, doc := range docs {
+				objs, _ := e.ListObjects(dbName, col, doc)
+				vals := []string{}
+				for _, obj := range objs {
+					for _, k := range []string{"name", "user"} {
+						if v, ok := obj.Fields[k]; ok {
+							vals = append(vals, k+"="+v.(string))
+						}
+					}
+				}
+				sort.Strings(vals)
+				out[dbName+"/"+col+"/"+doc] = vals
+			}
+		}
+	}
+	return out
+}
+
+func TestTx(t *testing.T) {
+	tests := []struct {
+		name string
+		// run trabaja con la transacción; between se ejecuta sobre el engine
+		// justo antes del commit (otro escritor)
+		run       func(tx *Tx) error
+		between   func(e *Engine) error
+		commitErr bool
+		want      map[string][]string
+	}{
+		{
+			name: "commit applies everything",
+			run: func(tx *Tx) error {
+				if _, err := tx.InsertObjects("shop", "users", "a", []map[string]interface{}{{"name": "a2"}}); err != nil {
+					return err
+				}
+				if err := tx.CreateDocument("shop", "orders", "p"); err != nil {
+					return err
+				}
+				_, err := tx.InsertObjects("shop", "orders", "p", []map[string]interface{}{{"user": "b"}})
+				return err
+			},
+			want: map[string][]string{
+				"shop/users/a":  {"name=a", "name=a2"},
+				"shop/users/b":  {"name=b"},
+				"shop/orders/o": {"user=a"},
+				"shop/orders/p": {"user=b"},
+			},
+		},
+		{
+			name: "delete and recreate a collection",
+			run: func(tx *Tx) error {
+				if err := tx.DeleteCollection("shop", "orders"); err != nil {
+					return err
+				}
+				if err := tx.CreateCollection("shop", "orders"); err != nil {
+					return err
+				}
+				return tx.CreateDocument("shop", "orders", "new")
+			},
+			want: map[string][]string{
+				"shop/users/a":    {"name=a"},
+				"shop/users/b":    {"name=b"},
+				"shop/orders/new": {},
+			},
+		},
+		{
+			name: "delete and recreate the database",
+			run: func(tx *Tx) error {
+				if err := tx.DeleteDatabase("shop"); err != nil {
+					return err
+				}
+				if err := tx.CreateDatabase("shop"); err != nil {
+					return err
+				}
+				return tx.CreateCollection("shop", "empty")
+			},
+			want: map[string][]string{},
+		},
+		{
+			name: "sees other writers on untouched documents",
+			run: func(tx *Tx) error {
+				_, err := tx.MergeObjects("shop", "users", "a", map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "A"})
+				return err
+			},
+			between: func(e *Engine) error {
+				_, err := e.InsertObject("shop", "users", "b", map[string]interface{}{"name": "b2"})
+				return err
+			},
+			want: map[string][]string{
+				"shop/users/a":  {"name=A"},
+				"shop/users/b":  {"name=b", "name=b2"},
+				"shop/orders/o": {"user=a"},
+			},
+		},
+		{
+			name: "aborts when a touched document is gone",
+			run: func(tx *Tx) error {
+				if _, err := tx.InsertObjects("shop", "users", "b", []map[string]interface{}{{"name": "b2"}}); err != nil {
+					return err
+				}
+				_, err := tx.InsertObjects("shop", "orders", "o", []map[string]interface{}{{"user": "b"}})
+				return err
+			},
+			between: func(e *Engine) error {
+				return e.DeleteDocument("shop", "orders", "o")
+			},
+			commitErr: true,
+			want: map[string][]string{
+				"shop/users/a": {"name=a"},
+				"shop/users/b": {"name=b"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := txEngine(t)
+			tx := e.Begin()
+			if err := tc.run(tx); err != nil {
+				t.Fatal(err)
+			}
+			if tc.between != nil {
+				if err := tc.between(e); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := tx.Commit()
+			if (err != nil) != tc.commitErr {
+				t.Fatalf("Commit() error = %v, want error %v", err, tc.commitErr)
+			}
+			if got := state(t, e); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("state after commit =\n%v\nwant\n%v", got, tc.want)
+			}
+			if err := tx.Commit(); !errors.Is(err, ErrTxDone) {
+				t.Errorf("second Commit() = %v, want ErrTxDone", err)
+			}
+		})
+	}
+}
+
+func TestTxRollbackAndPendingView(t *testing.T) {
+	e := txEngine(t)
+	before := state(t, e)
+	tx := e.Begin()
+	if err := tx.CreateCollection("shop", "tmp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.DeleteCollection("shop", "orders"); err != nil {
+		t.Fatal(err)
+	}
+	cols, _ := tx.ListCollections("shop")
+	sort.Strings(cols)
+	if want := []string{"tmp", "users"}; !reflect.DeepEqual(cols, want) {
+		t.Errorf("tx.ListCollections = %v, want %v", cols, want)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if got := state(t, e); !reflect.DeepEqual(got, before) {
+		t.Errorf("state after rollback = %v, want %v", got, before)
+	}
+}
+
+// El commit solo copia lo que toca: los documentos que no cambian siguen siendo
+// los mismos y el que cambia conserva su identidad (adopta la copia validada).
+func TestTxCommitTouchesOnlyItsDocuments(t *testing.T) {
+	e := txEngine(t)
+	users := e.Databases["shop"].Collections["users"]
+	a, b := users.Documents["a"], users.Documents["b"]
+	bObjs := b.Objects
+	orders := e.Databases["shop"].Collections["orders"]
+
+	tx := e.Begin()
+	if _, err := tx.InsertObjects("shop", "users", "a", []map[string]interface{}{{"name": "a2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if e.Databases["shop"].Collections["users"] != users || e.Databases["shop"].Collections["orders"] != orders {
+		t.Error("commit replaced a collection it did not create")
+	}
+	if users.Documents["a"] != a || users.Documents["b"] != b {
+		t.Error("commit replaced a document instead of updating it")
+	}
+	if &b.Objects[0] != &bObjs[0] {
+		t.Error("commit copied an untouched document")
+	}
+	if got := len(a.Objects); got != 2 {
+		t.Errorf("document a has %d objects, want 2", got)
+	}
+	if ids := a.Index().Lookup("name", idx.KeyOf("a2")); !reflect.DeepEqual(ids, []int{1}) {
+		t.Errorf("index of a has name=a2 at %v, want [1]", ids)
+	}
+}
+
+// Lo que un commit deja en el journal reproduce el mismo estado, con las mismas
+// claves generadas.
+func TestTxJournalReplay(t *testing.T) {
+	e := txEngine(t)
+	if err := e.SetCollectionKeys("shop", "users", db.KeysUUID); err != nil {
+		t.Fatal(err)
+	}
+	j := &memJournal{}
+	e.SetJournal(j)
+
+	tx := e.Begin()
+	if _, err := tx.InsertObjects("shop", "users", "a", []map[string]interface{}{{"name": "a2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.DeleteDocument("shop", "orders", "o"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if len(j.muts) != 1 || j.muts[0].Op != MutTx {
+		t.Fatalf("journal = %+v, want a single tx", j.muts)
+	}
+
+	replayed := txEngine(t)
+	if err := replayed.SetCollectionKeys("shop", "users", db.KeysUUID); err != nil {
+		t.Fatal(err)
+	}
+	if err := replayed.Apply(j.muts[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := state(t, replayed), state(t, e); !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed state = %v, want %v", got, want)
+	}
+	key := func(e *Engine) interface{} {
+		objs, _ := e.ListObjects("shop", "users", "a")
+		return objs[len(objs)-1].Fields[db.KeyField]
+	}
+	if key(replayed) != key(e) || key(e) == nil {
+		t.Errorf("replayed key = %v, want %v", key(replayed), key(e))
+	}
+}
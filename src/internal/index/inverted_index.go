@@ -1,19 +1,14 @@
 package index
 
-import (
-	"sync"
-)
-
 type ObjectRef struct {
 	DB         string `bson:"db"`
 	Collection string `bson:"collection"`
 	Document   string `bson:"document"`
 	ID         int    `bson:"id"`
-	mu         sync.RWMutex
 }
 
-// InvertedIndex: campo -> valor -> lista de refs
-type InvertedIndex map[string]map[string][]ObjectRef
+// InvertedIndex: campo -> valor (clave tipada) -> lista de refs
+type InvertedIndex map[string]map[Key][]ObjectRef
 
 func createIndexDocuments(path string) {
 
@@ -1,27 +1,150 @@
 package index
 
-type JoinIndex struct{ 
+import "sort"
+
+// JoinIndex resuelve joins entre dos colecciones usando solo el InvertedIndex:
+// para cada valor del campo izquierdo se buscan las refs con el mismo valor en
+// el campo derecho (hash join sobre las claves tipadas).
+type JoinIndex struct {
 	Index InvertedIndex
 }
 
-func NewJoinIndex () *JoinIndex{
+func NewJoinIndex(index InvertedIndex) *JoinIndex {
 	return &JoinIndex{
-		Index InvertedIndex
-	} 
+		Index: index,
+	}
+}
+
+// JoinSide describe un lado del join. All son todos los objetos del lado: hace
+// falta en left/right/outer para emitir los que no tienen pareja, y al unir por id.
+type JoinSide struct {
+	DB         string
+	Collection string
+	Field      string
+	All        []ObjectRef
+}
+
+// JoinPair es una fila del join. Left o Right es nil cuando ese lado no tiene pareja.
+type JoinPair struct {
+	Left  *ObjectRef
+	Right *ObjectRef
+}
+
+func (s JoinSide) owns(ref ObjectRef) bool {
+	return ref.DB == s.DB && ref.Collection == s.Collection
+}
+
+// keyed agrupa las refs de un lado por el valor de su campo de join. "id"/"_id"
+// se une por Object.ID (como en los filtros), así que ese lado necesita All.
+func (j *JoinIndex) keyed(s JoinSide) map[Key][]ObjectRef {
+	out := make(map[Key][]ObjectRef)
+	if s.Field == "id" || s.Field == "_id" {
+		for _, ref := range s.All {
+			k := KeyOf(ref.ID)
+			out[k] = append(out[k], ref)
+		}
+		return out
+	}
+	for key, refs := range j.Index[s.Field] {
+		for _, ref := range refs {
+			if s.owns(ref) {
+				out[key] = append(out[key], ref)
+			}
+		}
+	}
+	return out
 }
 
-func (s* SearchIndex) JoinPropertiesInner (query string) *String {
-    return "" 
+// matches devuelve los pares con igual valor en left.Field y right.Field, ordenados.
+func (j *JoinIndex) matches(left, right JoinSide) []JoinPair {
+	var pairs []JoinPair
+	rightVals := j.keyed(right)
+	for key, lrefs := range j.keyed(left) {
+		for n := range lrefs {
+			for m := range rightVals[key] {
+				l, r := lrefs[n], rightVals[key][m]
+				pairs = append(pairs, JoinPair{Left: &l, Right: &r})
+			}
+		}
+	}
+	sortPairs(pairs)
+	return pairs
 }
 
-func (s* SearchIndex) JoinPropertiesOuter (query string) *String {
-    return "" 
+func (j *JoinIndex) JoinPropertiesInner(left, right JoinSide) []JoinPair {
+	return j.matches(left, right)
 }
 
-func (s* SearchIndex) JoinPropertiesleft (query string) *String {
-    return "" 
+func (j *JoinIndex) JoinPropertiesOuter(left, right JoinSide) []JoinPair {
+	pairs := j.matches(left, right)
+	pairs = append(pairs, unmatched(pairs, left.All, true)...)
+	pairs = append(pairs, unmatched(pairs, right.All, false)...)
+	return pairs
 }
 
-func (s* SearchIndex) JoinPropertiesRight (query string) *String {
-    return "" 
-}
\ No newline at end of file
+func (j *JoinIndex) JoinPropertiesleft(left, right JoinSide) []JoinPair {
+	pairs := j.matches(left, right)
+	return append(pairs, unmatched(pairs, left.All, true)...)
+}
+
+func (j *JoinIndex) JoinPropertiesRight(left, right JoinSide) []JoinPair {
+	pairs := j.matches(left, right)
+	return append(pairs, unmatched(pairs, right.All, false)...)
+}
+
+// unmatched devuelve un par con un solo lado por cada ref de all que no aparezca en pairs.
+func unmatched(pairs []JoinPair, all []ObjectRef, leftSide bool) []JoinPair {
+	seen := make(map[ObjectRef]bool, len(pairs))
+	for _, p := range pairs {
+		if leftSide && p.Left != nil {
+			seen[*p.Left] = true
+		} else if !leftSide && p.Right != nil {
+			seen[*p.Right] = true
+		}
+	}
+	var out []JoinPair
+	for n := range all {
+		if seen[all[n]] {
+			continue
+		}
+		ref := all[n]
+		if leftSide {
+			out = append(out, JoinPair{Left: &ref})
+		} else {
+			out = append(out, JoinPair{Right: &ref})
+		}
+	}
+	sortPairs(out)
+	return out
+}
+
+func sortPairs(pairs []JoinPair) {
+	sort.SliceStable(pairs, func(a, b int) bool {
+		if c := compareRefs(pairs[a].Left, pairs[b].Left); c != 0 {
+			return c < 0
+		}
+		return compareRefs(pairs[a].Right, pairs[b].Right) < 0
+	})
+}
+
+func compareRefs(a, b *ObjectRef) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	case a.Document != b.Document:
+		if a.Document < b.Document {
+			return -1
+		}
+		return 1
+	case a.ID != b.ID:
+		if a.ID < b.ID {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
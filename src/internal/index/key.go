@@ -0,0 +1,120 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// KeyType es la etiqueta de tipo de una clave del índice.
+type KeyType uint8
+
+const (
+	KeyNull KeyType = iota
+	KeyBool
+	KeyNumber
+	KeyString
+	KeyOther // slices, mapas u otros valores: se codifican como JSON
+)
+
+// Key es la clave tipada de un valor dentro del índice invertido: etiqueta de tipo
+// más una codificación canónica. Así el int 18 y el float 18.0 comparten clave
+// (mismo número), pero el string "18" no.
+type Key struct {
+	Type  KeyType
+	Value string
+}
+
+// KeyOf construye la clave canónica de un valor de Object.Fields.
+func KeyOf(v interface{}) Key {
+	switch tv := v.(type) {
+	case nil:
+		return Key{Type: KeyNull}
+	case bool:
+		return Key{Type: KeyBool, Value: strconv.FormatBool(tv)}
+	case string:
+		return Key{Type: KeyString, Value: tv}
+	case int:
+		return Key{Type: KeyNumber, Value: strconv.FormatInt(int64(tv), 10)}
+	case int8:
+		return Key{Type: KeyNumber, Value: strconv.FormatInt(int64(tv), 10)}
+	case int16:
+		return Key{Type: KeyNumber, Value: strconv.FormatInt(int64(tv), 10)}
+	case int32:
+		return Key{Type: KeyNumber, Value: strconv.FormatInt(int64(tv), 10)}
+	case int64:
+		return Key{Type: KeyNumber, Value: strconv.FormatInt(tv, 10)}
+	case uint:
+		return Key{Type: KeyNumber, Value: strconv.FormatUint(uint64(tv), 10)}
+	case uint8:
+		return Key{Type: KeyNumber, Value: strconv.FormatUint(uint64(tv), 10)}
+	case uint16:
+		return Key{Type: KeyNumber, Value: strconv.FormatUint(uint64(tv), 10)}
+	case uint32:
+		return Key{Type: KeyNumber, Value: strconv.FormatUint(uint64(tv), 10)}
+	case uint64:
+		return Key{Type: KeyNumber, Value: strconv.FormatUint(tv, 10)}
+	case float32:
+		return numberKey(float64(tv))
+	case float64:
+		return numberKey(tv)
+	case json.Number:
+		if i, err := tv.Int64(); err == nil {
+			return Key{Type: KeyNumber, Value: strconv.FormatInt(i, 10)}
+		}
+		if f, err := tv.Float64(); err == nil {
+			return numberKey(f)
+		}
+		return Key{Type: KeyString, Value: tv.String()}
+	default:
+		b, err := json.Marshal(tv)
+		if err != nil {
+			return Key{Type: KeyOther, Value: fmt.Sprintf("%v", tv)}
+		}
+		return Key{Type: KeyOther, Value: string(b)}
+	}
+}
+
+// numberKey codifica floats enteros igual que los ints para que 18 y 18.0 coincidan.
+func numberKey(f float64) Key {
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return Key{Type: KeyNumber, Value: strconv.FormatInt(int64(f), 10)}
+	}
+	return Key{Type: KeyNumber, Value: strconv.FormatFloat(f, 'g', -1, 64)}
+}
+
+// KeyFromText interpreta el valor de una consulta "campo:valor" igual que el parser
+// interpreta literales: números, true/false, null, y strings entre comillas simples
+// o dobles. Cualquier otro texto es un string.
+func KeyFromText(s string) Key {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "null":
+		return Key{Type: KeyNull}
+	case "true", "false":
+		return Key{Type: KeyBool, Value: s}
+	}
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return Key{Type: KeyString, Value: s[1 : len(s)-1]}
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return Key{Type: KeyNumber, Value: strconv.FormatInt(i, 10)}
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return numberKey(f)
+	}
+	return Key{Type: KeyString, Value: s}
+}
+
+func (k Key) String() string {
+	switch k.Type {
+	case KeyNull:
+		return "null"
+	case KeyString:
+		return strconv.Quote(k.Value)
+	default:
+		return k.Value
+	}
+}
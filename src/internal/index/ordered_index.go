@@ -0,0 +1,213 @@
+package index
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+const (
+	skipMaxLevel = 16
+	skipP        = 0.25
+)
+
+// Bound es un extremo de un rango. Inclusive distingue <= / >= de < / >.
+type Bound struct {
+	Key       Key
+	Inclusive bool
+}
+
+// OrderedIndex: campo -> skiplist con las claves distintas del campo en orden.
+// Solo guarda claves; las refs siguen en el InvertedIndex, así que un rango se
+// resuelve en dos pasos: claves del rango aquí y refs de cada clave allí.
+type OrderedIndex map[string]*SkipList
+
+// Add registra key para field (no hace nada si ya estaba).
+func (o OrderedIndex) Add(field string, key Key) {
+	s, ok := o[field]
+	if !ok {
+		s = NewSkipList()
+		o[field] = s
+	}
+	s.Insert(key)
+}
+
+// Remove quita key de field y borra el campo si queda vacío.
+func (o OrderedIndex) Remove(field string, key Key) {
+	s, ok := o[field]
+	if !ok {
+		return
+	}
+	s.Delete(key)
+	if s.Len() == 0 {
+		delete(o, field)
+	}
+}
+
+// Range devuelve en orden las claves de field entre lo y hi (cualquiera puede ser nil).
+// Solo se comparan claves del mismo tipo que los extremos: "age>30" no devuelve strings.
+func (o OrderedIndex) Range(field string, lo, hi *Bound) []Key {
+	s, ok := o[field]
+	if !ok {
+		return nil
+	}
+	return s.Range(lo, hi)
+}
+
+type skipNode struct {
+	key  Key
+	next []*skipNode
+}
+
+// SkipList es una lista ordenada de claves con búsqueda e inserción O(log n) esperado.
+type SkipList struct {
+	head   *skipNode
+	level  int
+	length int
+}
+
+func NewSkipList() *SkipList {
+	return &SkipList{
+		head:  &skipNode{next: make([]*skipNode, skipMaxLevel)},
+		level: 1,
+	}
+}
+
+func (s *SkipList) Len() int {
+	return s.length
+}
+
+// Insert añade key si no existía. Devuelve false si ya estaba.
+func (s *SkipList) Insert(key Key) bool {
+	update := make([]*skipNode, skipMaxLevel)
+	x := s.head
+	for l := s.level - 1; l >= 0; l-- {
+		for x.next[l] != nil && CompareKeys(x.next[l].key, key) < 0 {
+			x = x.next[l]
+		}
+		update[l] = x
+	}
+	if n := x.next[0]; n != nil && CompareKeys(n.key, key) == 0 {
+		return false
+	}
+
+	lvl := randomLevel()
+	if lvl > s.level {
+		for l := s.level; l < lvl; l++ {
+			update[l] = s.head
+		}
+		s.level = lvl
+	}
+	n := &skipNode{key: key, next: make([]*skipNode, lvl)}
+	for l := 0; l < lvl; l++ {
+		n.next[l] = update[l].next[l]
+		update[l].next[l] = n
+	}
+	s.length++
+	return true
+}
+
+// Delete quita key. Devuelve false si no estaba.
+func (s *SkipList) Delete(key Key) bool {
+	update := make([]*skipNode, skipMaxLevel)
+	x := s.head
+	for l := s.level - 1; l >= 0; l-- {
+		for x.next[l] != nil && CompareKeys(x.next[l].key, key) < 0 {
+			x = x.next[l]
+		}
+		update[l] = x
+	}
+	n := x.next[0]
+	if n == nil || CompareKeys(n.key, key) != 0 {
+		return false
+	}
+	for l := 0; l < s.level; l++ {
+		if update[l].next[l] != n {
+			break
+		}
+		update[l].next[l] = n.next[l]
+	}
+	for s.level > 1 && s.head.next[s.level-1] == nil {
+		s.level--
+	}
+	s.length--
+	return true
+}
+
+// Range recorre las claves entre lo y hi. El tipo del rango lo fija el extremo no nil.
+func (s *SkipList) Range(lo, hi *Bound) []Key {
+	var typ KeyType
+	switch {
+	case lo != nil:
+		typ = lo.Key.Type
+	case hi != nil:
+		typ = hi.Key.Type
+	default:
+		return nil
+	}
+
+	before := func(k Key) bool {
+		if k.Type != typ {
+			return k.Type < typ
+		}
+		if lo == nil {
+			return false
+		}
+		c := CompareKeys(k, lo.Key)
+		return c < 0 || (c == 0 && !lo.Inclusive)
+	}
+
+	x := s.head
+	for l := s.level - 1; l >= 0; l-- {
+		for x.next[l] != nil && before(x.next[l].key) {
+			x = x.next[l]
+		}
+	}
+
+	var keys []Key
+	for x = x.next[0]; x != nil; x = x.next[0] {
+		if x.key.Type != typ {
+			break
+		}
+		if hi != nil {
+			c := CompareKeys(x.key, hi.Key)
+			if c > 0 || (c == 0 && !hi.Inclusive) {
+				break
+			}
+		}
+		keys = append(keys, x.key)
+	}
+	return keys
+}
+
+func randomLevel() int {
+	lvl := 1
+	for lvl < skipMaxLevel && rand.Float64() < skipP {
+		lvl++
+	}
+	return lvl
+}
+
+// CompareKeys ordena primero por tipo (null < bool < number < string < other)
+// y dentro del tipo por valor: numérico para números, lexicográfico para el resto.
+func CompareKeys(a, b Key) int {
+	if a.Type != b.Type {
+		if a.Type < b.Type {
+			return -1
+		}
+		return 1
+	}
+	if a.Type == KeyNumber {
+		fa, errA := strconv.ParseFloat(a.Value, 64)
+		fb, errB := strconv.ParseFloat(b.Value, 64)
+		if errA == nil && errB == nil {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+		}
+	}
+	return strings.Compare(a.Value, b.Value)
+}
@@ -0,0 +1,91 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+)
+
+func incl(v interface{}) *Bound { return &Bound{Key: KeyOf(v), Inclusive: true} }
+func excl(v interface{}) *Bound { return &Bound{Key: KeyOf(v)} }
+
+func keysOf(vals ...interface{}) []Key {
+	var keys []Key
+	for _, v := range vals {
+		keys = append(keys, KeyOf(v))
+	}
+	return keys
+}
+
+func TestSkipListRange(t *testing.T) {
+	s := NewSkipList()
+	for _, v := range []interface{}{10, 2, "b", -3, 5, nil, 2.5, "a", true, 1, "c", 2.0} {
+		s.Insert(KeyOf(v))
+	}
+
+	tests := []struct {
+		name   string
+		lo, hi *Bound
+		want   []Key
+	}{
+		{"inclusive", incl(2), incl(5), keysOf(2, 2.5, 5)},
+		{"exclusive", excl(2), excl(5), keysOf(2.5)},
+		{"numeric not lexicographic order", incl(2), incl(10), keysOf(2, 2.5, 5, 10)},
+		{"only upper bound", nil, incl(2), keysOf(-3, 1, 2)},
+		{"only lower bound", incl(5), nil, keysOf(5, 10)},
+		{"bounds between keys", incl(1.5), incl(6), keysOf(2, 2.5, 5)},
+		{"single key", incl(2), incl(2), keysOf(2)},
+		{"single key exclusive", excl(2), incl(2), nil},
+		{"above every key", excl(10), nil, nil},
+		{"below every key", nil, excl(-3), nil},
+		{"empty range", incl(5), incl(2), nil},
+		{"strings", incl("a"), excl("c"), keysOf("a", "b")},
+		{"strings from lower bound", excl("a"), nil, keysOf("b", "c")},
+		{"no bounds", nil, nil, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := s.Range(tc.lo, tc.hi); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Range = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSkipListInsertDelete(t *testing.T) {
+	s := NewSkipList()
+	for n := 0; n < 100; n++ {
+		if !s.Insert(KeyOf(n)) {
+			t.Fatalf("Insert(%d) = false on a new key", n)
+		}
+	}
+	if s.Insert(KeyOf(50.0)) {
+		t.Error("Insert(50.0) = true, 50 was already there")
+	}
+	for n := 0; n < 100; n += 2 {
+		if !s.Delete(KeyOf(n)) {
+			t.Fatalf("Delete(%d) = false on an existing key", n)
+		}
+	}
+	if s.Delete(KeyOf(0)) {
+		t.Error("Delete(0) = true twice")
+	}
+	if s.Len() != 50 {
+		t.Errorf("Len = %d, want 50", s.Len())
+	}
+	if got, want := s.Range(incl(10), excl(16)), keysOf(11, 13, 15); !reflect.DeepEqual(got, want) {
+		t.Errorf("Range after deletes = %v, want %v", got, want)
+	}
+}
+
+func TestOrderedIndexRemoveLastKey(t *testing.T) {
+	o := make(OrderedIndex)
+	o.Add("age", KeyOf(30))
+	o.Add("age", KeyOf(30))
+	o.Remove("age", KeyOf(30))
+	if _, ok := o["age"]; ok {
+		t.Error("field still indexed after removing its only key")
+	}
+	if got := o.Range("age", incl(0), nil); got != nil {
+		t.Errorf("Range on a removed field = %v", got)
+	}
+}
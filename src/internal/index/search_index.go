@@ -1,18 +1,19 @@
 package index
 
-type SearchIndex struct{ 
+type SearchIndex struct {
 	Index InvertedIndex
 }
 
-func NewSearchIndex () *SearchIndex{
+func NewSearchIndex() *SearchIndex {
 	return &SearchIndex{
-		Index InvertedIndex
-	} 
+		Index: make(InvertedIndex),
+	}
 }
 
-func (s* SearchIndex) findDocument (query string) *String {
-    return "" 
+func (s *SearchIndex) findDocument(query string) string {
+	return ""
+}
+
+func (s *SearchIndex) findProperty(query string) string {
+	return ""
 }
-func (s* SearchIndex) findProperty (query string) *String {
-    return "" 
-}      
\ No newline at end of file
@@ -0,0 +1,137 @@
+package index
+
+// Shard es el índice de un solo documento: campo -> valor (clave tipada) -> IDs de
+// objeto, más las claves ordenadas de cada campo para los rangos. El índice
+// invertido del engine está repartido así, un shard por documento, para que cada
+// uno se proteja con el lock de su documento y las escrituras en documentos
+// distintos no compitan por un índice global.
+//
+// Los métodos de lectura aceptan un Shard nil (documento sin indexar todavía).
+type Shard struct {
+	Inverted map[string]map[Key][]int
+	Ordered  OrderedIndex
+}
+
+func NewShard() *Shard {
+	return &Shard{
+		Inverted: make(map[string]map[Key][]int),
+		Ordered:  make(OrderedIndex),
+	}
+}
+
+// Add indexa cada campo k:v del objeto id.
+func (s *Shard) Add(id int, fields map[string]interface{}) {
+	for k, v := range fields {
+		key := KeyOf(v)
+		valMap, ok := s.Inverted[k]
+		if !ok {
+			valMap = make(map[Key][]int)
+			s.Inverted[k] = valMap
+		}
+		if len(valMap[key]) == 0 {
+			s.Ordered.Add(k, key)
+		}
+		valMap[key] = append(valMap[key], id)
+	}
+}
+
+// AddAll indexa de una vez los objetos ids[n] con campos fields[n]: primero los
+// agrupa por campo y valor y después añade cada grupo, así que cada clave entra
+// una sola vez en el índice ordenado aunque la compartan muchos objetos.
+func (s *Shard) AddAll(ids []int, fields []map[string]interface{}) {
+	pending := make(map[string]map[Key][]int)
+	for n, id := range ids {
+		for k, v := range fields[n] {
+			if pending[k] == nil {
+				pending[k] = make(map[Key][]int)
+			}
+			key := KeyOf(v)
+			pending[k][key] = append(pending[k][key], id)
+		}
+	}
+	for k, keys := range pending {
+		valMap, ok := s.Inverted[k]
+		if !ok {
+			valMap = make(map[Key][]int, len(keys))
+			s.Inverted[k] = valMap
+		}
+		for key, group := range keys {
+			if len(valMap[key]) == 0 {
+				s.Ordered.Add(k, key)
+			}
+			valMap[key] = append(valMap[key], group...)
+		}
+	}
+}
+
+// Remove quita id de cada campo k:v (los valores que se indexaron), limpiando
+// los valores y campos que queden vacíos.
+func (s *Shard) Remove(id int, fields map[string]interface{}) {
+	for k, v := range fields {
+		key := KeyOf(v)
+		valMap, ok := s.Inverted[k]
+		if !ok {
+			continue
+		}
+		ids, ok := valMap[key]
+		if !ok {
+			continue
+		}
+		kept := ids[:0]
+		for _, other := range ids {
+			if other != id {
+				kept = append(kept, other)
+			}
+		}
+		if len(kept) == 0 {
+			delete(valMap, key)
+			s.Ordered.Remove(k, key)
+		} else {
+			valMap[key] = kept
+		}
+		if len(valMap) == 0 {
+			delete(s.Inverted, k)
+		}
+	}
+}
+
+// Update reindexa id de old a next tocando solo los campos cuyo valor cambió.
+func (s *Shard) Update(id int, old, next map[string]interface{}) {
+	removed := make(map[string]interface{})
+	added := make(map[string]interface{})
+	for k, v := range old {
+		if nv, ok := next[k]; !ok || KeyOf(nv) != KeyOf(v) {
+			removed[k] = v
+		}
+	}
+	for k, v := range next {
+		if ov, ok := old[k]; !ok || KeyOf(ov) != KeyOf(v) {
+			added[k] = v
+		}
+	}
+	s.Remove(id, removed)
+	s.Add(id, added)
+}
+
+// Lookup devuelve los IDs con field igual a key, en orden de inserción. El slice
+// está recortado a su capacidad: un append del llamador no escribe en el índice.
+func (s *Shard) Lookup(field string, key Key) []int {
+	if s == nil {
+		return nil
+	}
+	ids := s.Inverted[field][key]
+	return ids[:len(ids):len(ids)]
+}
+
+// Range devuelve los IDs de las claves de field entre lo y hi (ver
+// OrderedIndex.Range), en orden de clave.
+func (s *Shard) Range(field string, lo, hi *Bound) []int {
+	if s == nil {
+		return nil
+	}
+	var ids []int
+	for _, key := range s.Ordered.Range(field, lo, hi) {
+		ids = append(ids, s.Inverted[field][key]...)
+	}
+	return ids
+}
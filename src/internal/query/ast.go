@@ -0,0 +1,45 @@
+package query
+
+import "fmt"
+
+// Expr es un nodo de la expresión de filtro de find where ...
+type Expr interface {
+	String() string
+}
+
+// LogicalExpr: Left and/or Right
+type LogicalExpr struct {
+	Op    string // "and" | "or"
+	Left  Expr
+	Right Expr
+}
+
+// NotExpr: not Expr
+type NotExpr struct {
+	Expr Expr
+}
+
+// CompareExpr: campo op valor, con op en = != < <= > >=
+type CompareExpr struct {
+	Field string
+	Op    string
+	Value interface{}
+}
+
+func (e *LogicalExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.Left, e.Op, e.Right)
+}
+
+func (e *NotExpr) String() string {
+	return fmt.Sprintf("not %s", e.Expr)
+}
+
+func (e *CompareExpr) String() string {
+	if s, ok := e.Value.(string); ok {
+		return fmt.Sprintf("%s %s %q", e.Field, e.Op, s)
+	}
+	if e.Value == nil {
+		return fmt.Sprintf("%s %s null", e.Field, e.Op)
+	}
+	return fmt.Sprintf("%s %s %v", e.Field, e.Op, e.Value)
+}
@@ -0,0 +1,99 @@
+package query
+
+import (
+	db "machDB/src/internal/db"
+	"sort"
+	"strings"
+)
+
+// Commands son las palabras con las que empieza un comando (ver ParseCommand).
+var Commands = []string{"list", "select", "create", "insert", "modify", "delete", "find", "import", "export", "begin", "commit", "rollback"}
+
+// Complete devuelve las palabras que pueden ir en la posición de la última
+// palabra de line (la que se está escribiendo), filtradas por su prefijo:
+// comandos, sus argumentos fijos y nombres de bases de datos, colecciones o
+// documentos según el contexto y la selección actual.
+func (i *Interpreter) Complete(line string) []string {
+	words := strings.Fields(line)
+	prefix := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\t") {
+		prefix = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	for n := range words {
+		words[n] = strings.ToLower(words[n])
+	}
+
+	var cands []string
+	switch {
+	case len(words) == 0:
+		cands = Commands
+	case len(words) == 1:
+		switch words[0] {
+		case "list":
+			cands = []string{"db", "collections", "documents"}
+		case "select":
+			cands = []string{"db", "collection"}
+		case "create", "delete":
+			cands = []string{"db", "collections", "documents"}
+		case "export":
+			cands = []string{"db", "collection", "document"}
+		case "find":
+			cands = append([]string{"where", "join", "inner", "left", "right", "outer", "in"}, i.collectionNames()...)
+		}
+	default:
+		last := words[len(words)-1]
+		switch {
+		case len(words) == 2 && (words[0] == "select" || words[0] == "delete") && last == "db":
+			cands = i.databaseNames()
+		case len(words) == 2 && words[0] == "select" && last == "collection",
+			len(words) == 2 && words[0] == "delete" && last == "collections":
+			cands = i.collectionNames()
+		case len(words) == 2 && (words[0] == "delete" || words[0] == "export") && strings.HasPrefix(last, "document"),
+			last == "document" && len(words) > 2:
+			cands = i.documentNames()
+		case len(words) == 3 && words[0] == "create" && words[1] == "collections":
+			cands = []string{"with"}
+		case len(words) == 4 && words[0] == "create" && last == "with":
+			cands = []string{"keys"}
+		case len(words) == 5 && words[0] == "create" && last == "keys":
+			cands = db.KeyKinds
+		case last == "in":
+			cands = append([]string{"document"}, i.collectionNames()...)
+		case words[0] == "find" && (last == "join" || len(words) > 2):
+			cands = i.collectionNames()
+		}
+	}
+
+	var out []string
+	for _, c := range cands {
+		if strings.HasPrefix(c, prefix) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (i *Interpreter) databaseNames() []string {
+	names := i.catalog().ListDatabases()
+	sort.Strings(names)
+	return names
+}
+
+func (i *Interpreter) collectionNames() []string {
+	if i.CurrentDB == "" {
+		return nil
+	}
+	names, _ := i.catalog().ListCollections(i.CurrentDB)
+	sort.Strings(names)
+	return names
+}
+
+func (i *Interpreter) documentNames() []string {
+	if i.CurrentDB == "" || i.CurrentColl == "" {
+		return nil
+	}
+	names, _ := i.catalog().ListDocuments(i.CurrentDB, i.CurrentColl)
+	sort.Strings(names)
+	return names
+}
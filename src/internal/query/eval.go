@@ -0,0 +1,148 @@
+package query
+
+import (
+	"fmt"
+
+	db "machDB/src/internal/db"
+	"machDB/src/internal/engine"
+	idx "machDB/src/internal/index"
+)
+
+// whereFilter adapta una Expr al engine.Filter: los índices dan los candidatos y
+// la expresión completa se vuelve a evaluar en memoria sobre cada uno.
+type whereFilter struct {
+	expr Expr
+}
+
+func (w whereFilter) Candidates(lookup func(engine.Predicate) []idx.ObjectRef) ([]idx.ObjectRef, bool) {
+	return candidates(w.expr, lookup)
+}
+
+func (w whereFilter) Match(obj *db.Object) bool {
+	return match(w.expr, obj)
+}
+
+// ParseWhere interpreta solo la expresión de un find where ("age >= 18 and
+// city = 'Lima'") y la devuelve lista para Engine.FindWhere.
+func ParseWhere(src string) (engine.Filter, error) {
+	p := NewParser(NewLexer(src))
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", engine.ErrBadQuery, err)
+	}
+	if p.curToken.Type != EOF {
+		return nil, fmt.Errorf("%w: unexpected %q after where expression", engine.ErrBadQuery, p.curToken.Value)
+	}
+	return whereFilter{expr: expr}, nil
+}
+
+var compareOps = map[string]string{
+	"=":  engine.OpEq,
+	"<":  engine.OpLt,
+	"<=": engine.OpLte,
+	">":  engine.OpGt,
+	">=": engine.OpGte,
+}
+
+// candidates devuelve un superconjunto de las refs que cumplen expr, o ok=false si
+// expr no se puede resolver con índices (!=, not, or con una rama no indexable).
+func candidates(expr Expr, lookup func(engine.Predicate) []idx.ObjectRef) ([]idx.ObjectRef, bool) {
+	switch e := expr.(type) {
+	case *CompareExpr:
+		op, ok := compareOps[e.Op]
+		if !ok {
+			return nil, false
+		}
+		return lookup(engine.Predicate{Field: e.Field, Op: op, Value: idx.KeyOf(e.Value)}), true
+	case *LogicalExpr:
+		left, lok := candidates(e.Left, lookup)
+		right, rok := candidates(e.Right, lookup)
+		if e.Op == "and" {
+			switch {
+			case lok && rok:
+				return intersectRefs(left, right), true
+			case lok:
+				return left, true
+			case rok:
+				return right, true
+			}
+			return nil, false
+		}
+		if lok && rok {
+			return unionRefs(left, right), true
+		}
+		return nil, false
+	default:
+		return nil, false
+	}
+}
+
+// match evalúa expr sobre obj. Un campo ausente solo cumple !=.
+func match(expr Expr, obj *db.Object) bool {
+	switch e := expr.(type) {
+	case *CompareExpr:
+		v, ok := obj.Fields[e.Field]
+		if !ok {
+			return e.Op == "!="
+		}
+		have, want := idx.KeyOf(v), idx.KeyOf(e.Value)
+		switch e.Op {
+		case "=":
+			return have == want
+		case "!=":
+			return have != want
+		}
+		if have.Type != want.Type {
+			return false
+		}
+		c := idx.CompareKeys(have, want)
+		switch e.Op {
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		case ">=":
+			return c >= 0
+		}
+		return false
+	case *LogicalExpr:
+		if e.Op == "and" {
+			return match(e.Left, obj) && match(e.Right, obj)
+		}
+		return match(e.Left, obj) || match(e.Right, obj)
+	case *NotExpr:
+		return !match(e.Expr, obj)
+	default:
+		return false
+	}
+}
+
+func intersectRefs(a, b []idx.ObjectRef) []idx.ObjectRef {
+	in := make(map[idx.ObjectRef]bool, len(b))
+	for _, r := range b {
+		in[r] = true
+	}
+	var out []idx.ObjectRef
+	for _, r := range a {
+		if in[r] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func unionRefs(a, b []idx.ObjectRef) []idx.ObjectRef {
+	seen := make(map[idx.ObjectRef]bool, len(a)+len(b))
+	out := make([]idx.ObjectRef, 0, len(a)+len(b))
+	for _, list := range [][]idx.ObjectRef{a, b} {
+		for _, r := range list {
+			if !seen[r] {
+				seen[r] = true
+				out = append(out, r)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"machDB/src/internal/engine"
+)
+
+func TestParseWhere(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string // Expr.String(), con los paréntesis que pone la precedencia
+	}{
+		{`age >= 18`, `age >= 18`},
+		{`age == 18`, `age = 18`},
+		{`age: 18`, `age = 18`},
+		{`city = "Lima"`, `city = "Lima"`},
+		{`city = 'Lima'`, `city = "Lima"`},
+		{`city = lima`, `city = "lima"`},
+		{`score > 1.5`, `score > 1.5`},
+		{`vip = true and note = null`, `(vip = true and note = null)`},
+		{`a = 1 or b = 2 and c = 3`, `(a = 1 or (b = 2 and c = 3))`},
+		{`a = 1 and b = 2 or c = 3`, `((a = 1 and b = 2) or c = 3)`},
+		{`(a = 1 or b = 2) and c = 3`, `((a = 1 or b = 2) and c = 3)`},
+		{`a = 1 || b = 2 && c = 3`, `(a = 1 or (b = 2 and c = 3))`},
+		{`not a = 1 and b = 2`, `(not a = 1 and b = 2)`},
+		{`!(a = 1 or b = 2)`, `not (a = 1 or b = 2)`},
+		{`not not a != 1`, `not not a != 1`},
+		{`a = 1 and b = 2 and c = 3`, `((a = 1 and b = 2) and c = 3)`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.src, func(t *testing.T) {
+			f, err := ParseWhere(tc.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.(whereFilter).expr.String(); got != tc.want {
+				t.Errorf("ParseWhere = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseWhereErrors(t *testing.T) {
+	for _, src := range []string{
+		``,
+		`age >`,
+		`age 18`,
+		`= 18`,
+		`(age = 18`,
+		`age = 18)`,
+		`age = 18 city = "Lima"`,
+		`age = 18 and`,
+		`age = 18 & city = "Lima"`,
+	} {
+		if f, err := ParseWhere(src); !errors.Is(err, engine.ErrBadQuery) {
+			t.Errorf("ParseWhere(%q) = %v, %v; want ErrBadQuery", src, f, err)
+		}
+	}
+}
+
+func TestWhereEval(t *testing.T) {
+	e := newTestEngine(t, "shop", docs{"people/d": {
+		{"name": "ana", "age": 17, "city": "Lima"},
+		{"name": "bob", "age": 18, "city": "Lima", "vip": true},
+		{"name": "cid", "age": 30, "city": "Cusco"},
+		{"name": "dan", "age": "40", "city": "Lima"},
+		{"name": "eva", "city": "Cusco", "vip": false},
+	}})
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{`age >= 18 and city = 'Lima'`, []string{"bob"}},
+		{`age >= 18 and city = "Lima"`, []string{"bob"}},
+		{`city = 'Cusco' or age < 18`, []string{"ana", "cid", "eva"}},
+		{`city = 'Cusco' or age < 18 and vip = true`, []string{"cid", "eva"}},
+		{`(city = 'Cusco' or age < 18) and vip = false`, []string{"eva"}},
+		{`not city = 'Lima'`, []string{"cid", "eva"}},
+		{`city != 'Lima'`, []string{"cid", "eva"}},
+		{`vip != true`, []string{"ana", "cid", "dan", "eva"}},
+		{`age > 0`, []string{"ana", "bob", "cid"}},
+		{`age = '40'`, []string{"dan"}},
+		{`age = 40`, nil},
+		{`name < 'c'`, []string{"ana", "bob"}},
+		{`missing = 1 or name = 'eva'`, []string{"eva"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.src, func(t *testing.T) {
+			f, err := ParseWhere(tc.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			objs, err := e.FindWhere(f, "shop")
+			if tc.want == nil {
+				if !errors.Is(err, engine.ErrNoResults) {
+					t.Fatalf("FindWhere = %v, %v; want ErrNoResults", objs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, obj := range objs {
+				names = append(names, obj.Fields["name"].(string))
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tc.want) {
+				t.Errorf("FindWhere = %v, want %v", names, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package query
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"machDB/src/internal/engine"
+)
+
+// docs describe el contenido de una base de datos de prueba: "colección/documento"
+// -> objetos. Un documento sin objetos se crea vacío.
+type docs map[string][]map[string]interface{}
+
+// newTestEngine crea un engine con la base de datos dbName y los documentos de
+// spec (y sus colecciones), en orden de nombre.
+func newTestEngine(t testing.TB, dbName string, spec docs) *engine.Engine {
+	t.Helper()
+	e := engine.NewEngine()
+	if err := e.CreateDatabase(dbName); err != nil {
+		t.Fatal(err)
+	}
+	paths := make([]string, 0, len(spec))
+	for path := range spec {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	cols := make(map[string]bool)
+	for _, path := range paths {
+		col, doc, ok := strings.Cut(path, "/")
+		if !ok {
+			t.Fatalf("bad document path %q, want collection/document", path)
+		}
+		if !cols[col] {
+			if err := e.CreateCollection(dbName, col); err != nil {
+				t.Fatal(err)
+			}
+			cols[col] = true
+		}
+		if err := e.CreateDocument(dbName, col, doc); err != nil {
+			t.Fatal(err)
+		}
+		if len(spec[path]) == 0 {
+			continue
+		}
+		if _, err := e.InsertObjects(dbName, col, doc, spec[path]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return e
+}
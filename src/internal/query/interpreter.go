@@ -1,35 +1,108 @@
 package query
 
 import (
+	"errors"
 	"fmt"
-	"machDB/src/internal/index"
+	db "machDB/src/internal/db"
+	"machDB/src/internal/engine"
+	"machDB/src/internal/storage"
+	"sort"
+	"strings"
 )
 
 type Interpreter struct {
 	DBPath      string
 	CurrentDB   string
 	CurrentColl string
-	idx         *index.Index
+	idx         *engine.Engine
+	store       *storage.Storage
+	tx          *engine.Tx
+}
+
+// writer son las escrituras que tienen tanto el engine como una transacción.
+type writer interface {
+	CreateDatabase(name string) error
+	CreateCollection(dbName, colName string) error
+	SetCollectionKeys(dbName, colName, keys string) error
+	CreateDocument(dbName, colName, docName string) error
+	InsertObjects(dbName, colName, docName string, objs []map[string]interface{}) ([]int, error)
+	MergeObjects(dbName, colName, docName string, filter, fields map[string]interface{}) ([]int, error)
+	ModifyObjects(dbName, colName, docName string, filter, updates map[string]interface{}) (int, error)
+	DeleteDatabase(dbName string) error
+	DeleteCollection(dbName, colName string) error
+	DeleteDocument(dbName, colName, docName string) error
+}
+
+// catalog son los listados de nombres; los de una transacción ven lo pendiente.
+type catalog interface {
+	ListDatabases() []string
+	ListCollections(dbName string) ([]string, error)
+	ListDocuments(dbName, colName string) ([]string, error)
+}
+
+// writer devuelve la transacción abierta o, si no hay, el engine.
+func (i *Interpreter) writer() writer {
+	if i.tx != nil {
+		return i.tx
+	}
+	return i.idx
+}
+
+func (i *Interpreter) catalog() catalog {
+	if i.tx != nil {
+		return i.tx
+	}
+	return i.idx
+}
+
+// InTx indica si hay una transacción abierta (begin sin commit ni rollback).
+func (i *Interpreter) InTx() bool {
+	return i.tx != nil
 }
 
 func NewInterpreter(dbpath string) (*Interpreter, error) {
 	interp := &Interpreter{
 		DBPath: dbpath,
-		idx:    index.NewIndex(),
+		idx:    engine.NewEngine(),
+		store:  storage.NewStorage(dbpath),
 	}
 
-	err := interp.idx.LoadFromDisk()
+	err := interp.store.LoadFromDisk(interp.idx)
 	if err != nil {
 		return nil, err
 	}
 
 	return interp, nil
 }
-func (i *Interpreter) Save() {
-	i.idx.FlushToDisk()
+func (i *Interpreter) Save() error {
+	return i.store.FlushToDisk(i.idx)
+}
+
+// Close cierra el WAL; los cambios posteriores ya no se registran.
+func (i *Interpreter) Close() error {
+	return i.store.Close(i.idx)
+}
+
+// Run parsea y ejecuta una línea de comando.
+func (i *Interpreter) Run(line string) (*Result, error) {
+	cmd, err := NewParser(NewLexer(line)).ParseCommand()
+	if err != nil {
+		return nil, err
+	}
+	return i.Execute(cmd)
 }
 
-func (i *Interpreter) Execute(cmd *Command) error {
+// Execute ejecuta cmd y devuelve su Result sin imprimir nada.
+func (i *Interpreter) Execute(cmd *Command) (*Result, error) {
+	res, err := i.execute(cmd)
+	if err != nil {
+		return nil, err
+	}
+	res.Command = cmd.Name
+	return res, nil
+}
+
+func (i *Interpreter) execute(cmd *Command) (*Result, error) {
 	switch cmd.Name {
 	case "list":
 		return i.cmdList(cmd.Args)
@@ -44,60 +117,67 @@ func (i *Interpreter) Execute(cmd *Command) error {
 	case "delete":
 		return i.cmdDelete(cmd.Args)
 	case "find":
-		return i.cmdFind(cmd.RawQuery, cmd.Args)
+		if cmd.Join != "" {
+			return i.cmdJoin(cmd.Join, cmd.RawQuery, cmd.Args)
+		}
+		return i.cmdFind(cmd.Where, cmd.RawQuery, cmd.Args)
 	case "import":
 		return i.cmdImport(cmd.Args)
 	case "export":
 		return i.cmdExport(cmd.Args)
+	case "begin":
+		return i.cmdBegin()
+	case "commit":
+		return i.cmdCommit()
+	case "rollback":
+		return i.cmdRollback()
 	default:
-		return fmt.Errorf("comando no implementado: %s", cmd.Name)
+		return nil, fmt.Errorf("comando no implementado: %s", cmd.Name)
 	}
 }
 
-func (i *Interpreter) cmdList(args []string) error {
+// cmdList devuelve los nombres ordenados.
+func (i *Interpreter) cmdList(args []string) (*Result, error) {
 	if len(args) == 0 {
-		return fmt.Errorf("list requiere argumento")
+		return nil, fmt.Errorf("list requiere argumento")
 	}
+	var names []string
+	var err error
 	switch args[0] {
 	case "db":
-		databases := i.idx.ListDatabases()
-		fmt.Println(databases)
+		names = i.catalog().ListDatabases()
 	case "collections":
 		if i.CurrentDB == "" {
-			return fmt.Errorf("no hay base de datos seleccionada")
-		}
-		collections, err := i.idx.ListCollections(i.CurrentDB)
-		if err != nil {
-			return err
+			return nil, fmt.Errorf("no hay base de datos seleccionada")
 		}
-		fmt.Println(collections)
+		names, err = i.catalog().ListCollections(i.CurrentDB)
 	case "documents":
 		if i.CurrentColl == "" {
-			return fmt.Errorf("no hay colección seleccionada")
+			return nil, fmt.Errorf("no hay colección seleccionada")
 		}
-		docs, err := i.idx.ListDocuments(i.CurrentDB, i.CurrentColl)
-		if err != nil {
-			return err
-		}
-		fmt.Println(docs)
+		names, err = i.catalog().ListDocuments(i.CurrentDB, i.CurrentColl)
 	default:
-		return fmt.Errorf("argumento desconocido para list: %s", args[0])
+		return nil, fmt.Errorf("argumento desconocido para list: %s", args[0])
 	}
-	return nil
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return &Result{Names: names}, nil
 }
 
-func (i *Interpreter) cmdSelect(args []string) error {
+func (i *Interpreter) cmdSelect(args []string) (*Result, error) {
 	if len(args) == 0 {
-		return fmt.Errorf("select requiere argumento")
+		return nil, fmt.Errorf("select requiere argumento")
 	}
 
 	switch args[0] {
 	case "db":
 		if len(args) < 2 {
-			return fmt.Errorf("select db requiere nombre de base de datos")
+			return nil, fmt.Errorf("select db requiere nombre de base de datos")
 		}
 		dbName := args[1]
-		dbs := i.idx.ListDatabases()
+		dbs := i.catalog().ListDatabases()
 		found := false
 		for _, d := range dbs {
 			if d == dbName {
@@ -106,24 +186,23 @@ func (i *Interpreter) cmdSelect(args []string) error {
 			}
 		}
 		if !found {
-			return fmt.Errorf("base de datos %s no encontrada", dbName)
+			return nil, fmt.Errorf("base de datos %s no encontrada", dbName)
 		}
 		i.CurrentDB = dbName
 		i.CurrentColl = ""
-		fmt.Println("Base de datos seleccionada:", i.CurrentDB)
-		return nil
+		return &Result{Message: "Base de datos seleccionada: " + i.CurrentDB}, nil
 
 	case "collection":
 		if i.CurrentDB == "" {
-			return fmt.Errorf("no hay base de datos seleccionada")
+			return nil, fmt.Errorf("no hay base de datos seleccionada")
 		}
 		if len(args) < 2 {
-			return fmt.Errorf("select collection requiere nombre de colección")
+			return nil, fmt.Errorf("select collection requiere nombre de colección")
 		}
 		colName := args[1]
-		cols, err := i.idx.ListCollections(i.CurrentDB)
+		cols, err := i.catalog().ListCollections(i.CurrentDB)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		found := false
 		for _, c := range cols {
@@ -133,72 +212,363 @@ func (i *Interpreter) cmdSelect(args []string) error {
 			}
 		}
 		if !found {
-			return fmt.Errorf("colección %s no encontrada en base de datos %s", colName, i.CurrentDB)
+			return nil, fmt.Errorf("colección %s no encontrada en base de datos %s", colName, i.CurrentDB)
 		}
 		i.CurrentColl = colName
-		fmt.Println("Colección seleccionada:", i.CurrentColl)
-		return nil
+		return &Result{Message: "Colección seleccionada: " + i.CurrentColl}, nil
 
 	default:
-		return fmt.Errorf("argumento desconocido para select: %s", args[0])
+		return nil, fmt.Errorf("argumento desconocido para select: %s", args[0])
 	}
 }
 
-// Implementa el resto con la lógica que necesites
-func (i *Interpreter) cmdCreate(args []string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("create needs an argument")
+// cmdCreate: create db|collections|documents <nombre>, o
+// create collections <nombre> with keys objectid|uuid
+func (i *Interpreter) cmdCreate(args []string) (*Result, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("create needs a kind and a name")
+	}
+	if len(args) > 2 && args[0] != "collections" {
+		return nil, fmt.Errorf("only collections can be created with keys")
 	}
+	var err error
 	switch args[0] {
 	case "db":
-		i.idx.CreateDatabase(args[1])
+		err = i.writer().CreateDatabase(args[1])
 	case "collections":
-		i.idx.CreateCollection(i.CurrentDB, args[1])
+		if len(args) > 2 {
+			if err := db.ValidKeys(args[2]); err != nil {
+				return nil, err
+			}
+		}
+		err = i.writer().CreateCollection(i.CurrentDB, args[1])
+		if err == nil && len(args) > 2 {
+			err = i.writer().SetCollectionKeys(i.CurrentDB, args[1], args[2])
+		}
 	case "documents":
-		i.idx.CreateDocument(i.CurrentDB, i.CurrentColl, args[1])
+		err = i.writer().CreateDocument(i.CurrentDB, i.CurrentColl, args[1])
 	default:
-		return fmt.Errorf("unkown argument for create: %s", args[0])
+		return nil, fmt.Errorf("unkown argument for create: %s", args[0])
 	}
-	return nil
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Affected: 1}, nil
 }
 
-func (i *Interpreter) cmdInsert(props, filters []map[string]interface{}, args []string) error {
-	fmt.Println("Comando insert no implementado aún")
-	return nil
+// cmdInsert soporta dos formas:
+//
+//	insert [{...},{...}] in document X       -> añade objetos nuevos
+//	insert {...} for {id:0} in document X    -> mezcla campos en los objetos que cumplan el filtro
+func (i *Interpreter) cmdInsert(props, filters []map[string]interface{}, args []string) (*Result, error) {
+	if i.CurrentDB == "" {
+		return nil, fmt.Errorf("no hay base de datos seleccionada")
+	}
+	if i.CurrentColl == "" {
+		return nil, fmt.Errorf("no hay colección seleccionada")
+	}
+	if len(args) < 2 {
+		return nil, fmt.Errorf("insert requiere nombre de documento")
+	}
+	docName := args[1]
+
+	if len(filters) == 0 {
+		ids, err := i.writer().InsertObjects(i.CurrentDB, i.CurrentColl, docName, props)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{IDs: ids, Affected: len(ids), Message: "Objetos insertados"}, nil
+	}
+
+	if len(props) != 1 {
+		return nil, fmt.Errorf("insert ... for requiere un único objeto de propiedades")
+	}
+	// Con varios filtros cada uno debe cumplirse: si uno falla no se aplica ninguno
+	var ids []int
+	err := i.atomically(func(w writer) error {
+		for _, filter := range filters {
+			merged, err := w.MergeObjects(i.CurrentDB, i.CurrentColl, docName, filter, props[0])
+			if err != nil {
+				return err
+			}
+			ids = append(ids, merged...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Result{IDs: ids, Affected: len(ids), Message: "Objetos actualizados"}, nil
 }
 
-func (i *Interpreter) cmdModify(props, filters []map[string]interface{}, args []string) error {
-	return nil
+// atomically ejecuta fn de forma que sus escrituras se apliquen todas o
+// ninguna: fuera de una transacción, en una propia que confirma al final; dentro,
+// con un savepoint al que vuelve si fn falla.
+func (i *Interpreter) atomically(fn func(w writer) error) error {
+	if i.tx == nil {
+		tx := i.idx.Begin()
+		if err := fn(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+	savepoint := i.tx.Len()
+	err := fn(i.tx)
+	if err == nil {
+		return nil
+	}
+	if terr := i.tx.Truncate(savepoint); terr != nil {
+		i.tx = nil
+		i.dropMissingSelection()
+		return fmt.Errorf("%v; %w", err, terr)
+	}
+	return err
 }
 
-func (i *Interpreter) cmdDelete(args []string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("delete needs an argument")
+// cmdModify: modify {age:30} for {id:1} in document X
+// Con varios filtros ([{id:0},{id:1}]) se modifican los objetos que cumplan cualquiera.
+func (i *Interpreter) cmdModify(props, filters []map[string]interface{}, args []string) (*Result, error) {
+	if i.CurrentDB == "" {
+		return nil, fmt.Errorf("no hay base de datos seleccionada")
+	}
+	if i.CurrentColl == "" {
+		return nil, fmt.Errorf("no hay colección seleccionada")
+	}
+	if len(args) < 2 {
+		return nil, fmt.Errorf("modify requiere nombre de documento")
+	}
+	if len(props) != 1 {
+		return nil, fmt.Errorf("modify requiere un único objeto de propiedades")
 	}
+	docName := args[1]
+
+	// Como en insert ... for: con varios filtros se aplican todos o ninguno
+	total := 0
+	err := i.atomically(func(w writer) error {
+		for _, filter := range filters {
+			n, err := w.ModifyObjects(i.CurrentDB, i.CurrentColl, docName, filter, props[0])
+			if err != nil {
+				return err
+			}
+			total += n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Affected: total}, nil
+}
+
+// cmdDelete: delete db|collections|documents <nombre>. Borrar la base de datos o
+// la colección seleccionada la deselecciona.
+func (i *Interpreter) cmdDelete(args []string) (*Result, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("delete needs a kind and a name")
+	}
+	var err error
 	switch args[0] {
 	case "db":
-		i.idx.DeleteDatabase(args[1])
+		if err = i.writer().DeleteDatabase(args[1]); err == nil && i.CurrentDB == args[1] {
+			i.CurrentDB, i.CurrentColl = "", ""
+		}
 	case "collections":
-		i.idx.DeleteCollection(i.CurrentDB, args[1])
+		if err = i.writer().DeleteCollection(i.CurrentDB, args[1]); err == nil && i.CurrentColl == args[1] {
+			i.CurrentColl = ""
+		}
 	case "documents":
-		i.idx.DeleteDocument(i.CurrentDB, i.CurrentColl, args[1])
+		err = i.writer().DeleteDocument(i.CurrentDB, i.CurrentColl, args[1])
 	default:
-		return fmt.Errorf("unkown argument for delete: %s", args[0])
+		return nil, fmt.Errorf("unkown argument for delete: %s", args[0])
 	}
-	return nil
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Affected: 1}, nil
 }
 
-func (i *Interpreter) cmdFind(rawQueries []string, args []string) error {
-	fmt.Println("Comando find no implementado aún")
-	return nil
+// cmdFind: find "name:Luis" "age>=18" "score:1..5" [in users] [orders ...]
+// o bien: find where age >= 18 and (city = "Lima" or city = "Bogotá") [in users]
+// Las consultas entre comillas deben cumplirse todas (AND). Sin colecciones
+// explícitas se busca en la colección seleccionada o, si no hay, en toda la base de datos.
+func (i *Interpreter) cmdFind(where Expr, rawQueries []string, args []string) (*Result, error) {
+	if i.CurrentDB == "" {
+		return nil, fmt.Errorf("no hay base de datos seleccionada")
+	}
+	if where == nil && len(rawQueries) == 0 {
+		return nil, fmt.Errorf("find requiere al menos una consulta \"campo:valor\" o where")
+	}
+
+	collections := args
+	if len(collections) == 0 && i.CurrentColl != "" {
+		collections = []string{i.CurrentColl}
+	}
+
+	var objs []*db.Object
+	var err error
+	if where != nil {
+		objs, err = i.idx.FindWhere(whereFilter{expr: where}, i.CurrentDB, collections...)
+	} else {
+		objs, err = i.idx.FindByQueries(rawQueries, i.CurrentDB, collections...)
+	}
+	if errors.Is(err, engine.ErrNoResults) {
+		objs, err = []*db.Object{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Objects: objs, Affected: len(objs)}, nil
 }
 
-func (i *Interpreter) cmdImport(args []string) error {
-	fmt.Println("Comando import no implementado aún")
-	return nil
+// cmdJoin: find [inner|left|right|outer] join "users.id=orders.user_id" [db] users orders
+// Sin db se usa la base de datos seleccionada. El prefijo "coleccion." de cada
+// lado de la condición es opcional.
+func (i *Interpreter) cmdJoin(kind string, rawQueries []string, args []string) (*Result, error) {
+	if len(rawQueries) != 1 {
+		return nil, fmt.Errorf("join requiere una única condición \"a.campo=b.campo\"")
+	}
+	dbName := i.CurrentDB
+	switch len(args) {
+	case 2:
+	case 3:
+		dbName, args = args[0], args[1:]
+	default:
+		return nil, fmt.Errorf("join requiere [db] coleccion1 coleccion2")
+	}
+	if dbName == "" {
+		return nil, fmt.Errorf("no hay base de datos seleccionada")
+	}
+	leftCol, rightCol := args[0], args[1]
+
+	left, right, ok := strings.Cut(rawQueries[0], "=")
+	if !ok {
+		return nil, fmt.Errorf("condición de join inválida: %s", rawQueries[0])
+	}
+	leftField := strings.TrimPrefix(strings.TrimSpace(left), leftCol+".")
+	rightField := strings.TrimPrefix(strings.TrimSpace(right), rightCol+".")
+
+	rows, err := i.idx.Join(kind, dbName, leftCol, leftField, rightCol, rightField)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Rows: rows, Affected: len(rows)}, nil
 }
 
-func (i *Interpreter) cmdExport(args []string) error {
-	fmt.Println("Comando export no implementado aún")
-	return nil
+// cmdImport: import <archivo>. Va a la base de datos y colección seleccionadas
+// (el JSON de export trae las suyas) y crea lo que falte.
+func (i *Interpreter) cmdImport(args []string) (*Result, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("import requiere archivo")
+	}
+	if i.tx != nil {
+		return nil, fmt.Errorf("import no se puede usar dentro de una transacción")
+	}
+	target := storage.Scope{DB: i.CurrentDB, Collection: i.CurrentColl}
+	report, err := storage.ImportFrom(i.idx, args[0], target)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Affected: report.Inserted,
+		Errors:   report.Errors,
+		Message: fmt.Sprintf("%s: %d fila(s) leída(s), %d objeto(s) importado(s), %d error(es)",
+			report.Format, report.Rows, report.Inserted, len(report.Errors)),
+	}, nil
+}
+
+// cmdExport: export db|collection <archivo> o export document <nombre> <archivo>.
+// El formato sale de la extensión: .json (jerárquico), .ndjson o .csv.
+func (i *Interpreter) cmdExport(args []string) (*Result, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("export requiere destino y archivo")
+	}
+	if i.CurrentDB == "" {
+		return nil, fmt.Errorf("no hay base de datos seleccionada")
+	}
+
+	scope := storage.Scope{DB: i.CurrentDB}
+	switch args[0] {
+	case "db":
+	case "collection", "collections":
+		if i.CurrentColl == "" {
+			return nil, fmt.Errorf("no hay colección seleccionada")
+		}
+		scope.Collection = i.CurrentColl
+	case "document", "documents":
+		if i.CurrentColl == "" {
+			return nil, fmt.Errorf("no hay colección seleccionada")
+		}
+		if len(args) < 3 {
+			return nil, fmt.Errorf("export document requiere nombre y archivo")
+		}
+		scope.Collection = i.CurrentColl
+		scope.Document = args[1]
+	default:
+		return nil, fmt.Errorf("argumento desconocido para export: %s", args[0])
+	}
+
+	path := args[len(args)-1]
+	n, err := storage.ExportTo(i.idx, scope, path)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Affected: n, Message: fmt.Sprintf("%d objeto(s) exportado(s) a %s", n, path)}, nil
+}
+
+// cmdBegin abre una transacción: create, insert, modify y delete quedan
+// pendientes hasta commit (se aplican todos o ninguno) o rollback. list y select
+// ven lo pendiente; find, join y export solo lo confirmado.
+func (i *Interpreter) cmdBegin() (*Result, error) {
+	if i.tx != nil {
+		return nil, fmt.Errorf("ya hay una transacción abierta")
+	}
+	i.tx = i.idx.Begin()
+	return &Result{Message: "Transacción iniciada"}, nil
+}
+
+// cmdCommit aplica la transacción abierta. Si falla no se aplica nada y la
+// transacción se cierra igualmente.
+func (i *Interpreter) cmdCommit() (*Result, error) {
+	if i.tx == nil {
+		return nil, fmt.Errorf("no hay transacción abierta")
+	}
+	tx := i.tx
+	i.tx = nil
+	if err := tx.Commit(); err != nil {
+		i.dropMissingSelection()
+		return nil, err
+	}
+	return &Result{Affected: tx.Len(), Message: fmt.Sprintf("Transacción confirmada: %d operación(es)", tx.Len())}, nil
+}
+
+// cmdRollback descarta la transacción abierta.
+func (i *Interpreter) cmdRollback() (*Result, error) {
+	if i.tx == nil {
+		return nil, fmt.Errorf("no hay transacción abierta")
+	}
+	n := i.tx.Len()
+	i.tx.Rollback()
+	i.tx = nil
+	i.dropMissingSelection()
+	return &Result{Message: fmt.Sprintf("Transacción descartada: %d operación(es)", n)}, nil
+}
+
+// dropMissingSelection deselecciona la base de datos o la colección que solo
+// existían dentro de la transacción descartada.
+func (i *Interpreter) dropMissingSelection() {
+	if i.CurrentDB == "" {
+		return
+	}
+	cols, err := i.idx.ListCollections(i.CurrentDB)
+	if err != nil {
+		i.CurrentDB, i.CurrentColl = "", ""
+		return
+	}
+	for _, c := range cols {
+		if c == i.CurrentColl {
+			return
+		}
+	}
+	i.CurrentColl = ""
 }
@@ -0,0 +1,300 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	db "machDB/src/internal/db"
+	"machDB/src/internal/engine"
+)
+
+// newTestInterpreter abre un intérprete sobre un directorio temporal con la base
+// de datos shop y la colección users seleccionadas y el documento d creado.
+func newTestInterpreter(t *testing.T) *Interpreter {
+	t.Helper()
+	i, err := NewInterpreter(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { i.Close() })
+	run(t, i, "create db shop", "select db shop", "create collections users", "select collection users", "create documents d")
+	return i
+}
+
+// run ejecuta cada línea y falla el test si alguna da error.
+func run(t *testing.T, i *Interpreter, lines ...string) []*Result {
+	t.Helper()
+	var results []*Result
+	for _, line := range lines {
+		res, err := i.Run(line)
+		if err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// countWhere cuenta los objetos de users que cumplen where (0 si no hay).
+func countWhere(t *testing.T, i *Interpreter, where string) int {
+	t.Helper()
+	res, err := i.Run("find where " + where)
+	if err != nil {
+		return 0
+	}
+	return len(res.Objects)
+}
+
+// Run devuelve en Result lo que produce cada comando, sin imprimir nada.
+func TestRunResults(t *testing.T) {
+	i := newTestInterpreter(t)
+	tests := []struct {
+		line  string
+		check func(t *testing.T, res *Result)
+	}{
+		{"list db", func(t *testing.T, res *Result) {
+			if !reflect.DeepEqual(res.Names, []string{"shop"}) {
+				t.Errorf("Names = %v", res.Names)
+			}
+		}},
+		{"create collections orders", func(t *testing.T, res *Result) {
+			if res.Affected != 1 {
+				t.Errorf("Affected = %d, want 1", res.Affected)
+			}
+		}},
+		{"list collections", func(t *testing.T, res *Result) {
+			if !reflect.DeepEqual(res.Names, []string{"orders", "users"}) {
+				t.Errorf("Names = %v", res.Names)
+			}
+		}},
+		{`insert [{name:"ana",age:30},{name:"bob",age:17}] in document d`, func(t *testing.T, res *Result) {
+			if !reflect.DeepEqual(res.IDs, []int{0, 1}) || res.Affected != 2 {
+				t.Errorf("IDs = %v, Affected = %d; want [0 1], 2", res.IDs, res.Affected)
+			}
+		}},
+		{`insert {vip:true} for {name:"ana"} in document d`, func(t *testing.T, res *Result) {
+			if !reflect.DeepEqual(res.IDs, []int{0}) {
+				t.Errorf("IDs = %v, want [0]", res.IDs)
+			}
+		}},
+		{`modify {age:18} for [{id:1},{name:"zzz"}] in document d`, func(t *testing.T, res *Result) {
+			if res.Affected != 1 {
+				t.Errorf("Affected = %d, want 1", res.Affected)
+			}
+		}},
+		{`find where age >= 18`, func(t *testing.T, res *Result) {
+			if len(res.Objects) != 2 || res.Affected != 2 {
+				t.Errorf("Objects = %v, Affected = %d; want 2 objects", res.Objects, res.Affected)
+			}
+		}},
+		{`find "vip:true"`, func(t *testing.T, res *Result) {
+			if len(res.Objects) != 1 || res.Objects[0].Fields["name"] != "ana" {
+				t.Errorf("Objects = %v, want ana", res.Objects)
+			}
+		}},
+		{`find where name = "nobody"`, func(t *testing.T, res *Result) {
+			if res.Objects == nil || len(res.Objects) != 0 {
+				t.Errorf("Objects = %#v, want an empty slice", res.Objects)
+			}
+		}},
+		{"select collection orders", func(t *testing.T, res *Result) {
+			if res.Message == "" || i.CurrentColl != "orders" {
+				t.Errorf("Message = %q, CurrentColl = %q", res.Message, i.CurrentColl)
+			}
+		}},
+		{"create documents o", nil},
+		{`insert [{user:"ana",total:5},{user:"cid",total:7}] in document o`, nil},
+		{`find join "users.name=orders.user" users orders`, func(t *testing.T, res *Result) {
+			if len(res.Rows) != 1 || res.Rows[0]["users.name"] != "ana" || res.Rows[0]["orders.total"] != 5 {
+				t.Errorf("Rows = %v, want one row joining ana with her order", res.Rows)
+			}
+		}},
+		{`find left join "orders.user=users.name" orders users`, func(t *testing.T, res *Result) {
+			if len(res.Rows) != 2 {
+				t.Errorf("Rows = %v, want 2", res.Rows)
+			}
+		}},
+		{"begin", nil},
+		{"delete documents o", nil},
+		{"commit", func(t *testing.T, res *Result) {
+			if res.Affected != 1 {
+				t.Errorf("Affected = %d, want 1", res.Affected)
+			}
+		}},
+		{"list documents", func(t *testing.T, res *Result) {
+			if len(res.Names) != 0 {
+				t.Errorf("Names = %v, want none", res.Names)
+			}
+		}},
+	}
+	for _, tc := range tests {
+		res, err := i.Run(tc.line)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.line, err)
+		}
+		if res.Command != strings.Fields(tc.line)[0] {
+			t.Errorf("%s: Command = %q", tc.line, res.Command)
+		}
+		if tc.check != nil {
+			t.Run(tc.line, func(t *testing.T) { tc.check(t, res) })
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	i := newTestInterpreter(t)
+	for _, line := range []string{
+		"frobnicate",
+		"list",
+		"select db nope",
+		"create db shop",
+		`insert [{name:"a"}] in document nope`,
+		"find",
+		"commit",
+		"export db out.xml",
+	} {
+		if res, err := i.Run(line); err == nil {
+			t.Errorf("%s: want error, got %+v", line, res)
+		}
+	}
+}
+
+// find no mezcla where con consultas entre comillas: antes las comillas se
+// descartaban sin avisar y ana y bob salían los dos.
+func TestFindRejectsWhereWithQueries(t *testing.T) {
+	i := newTestInterpreter(t)
+	run(t, i, `insert [{name:"ana",age:30},{name:"bob",age:40}] in document d`)
+	for _, line := range []string{
+		`find where age >= 18 "name:ana"`,
+		`find "name:ana" where age >= 18`,
+	} {
+		if res, err := i.Run(line); err == nil {
+			t.Errorf("%s: want error, got %d objects", line, len(res.Objects))
+		}
+	}
+	if n := countWhere(t, i, `age >= 18 and name = "ana"`); n != 1 {
+		t.Errorf("find where with both conditions = %d objects, want 1", n)
+	}
+}
+
+// Lo confirmado sobrevive a cerrar y volver a abrir el directorio.
+func TestInterpreterReopen(t *testing.T) {
+	dir := t.TempDir()
+	i, err := NewInterpreter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, i, "create db shop", "select db shop", "create collections users", "select collection users",
+		"create documents d", `insert [{name:"a"},{name:"b"}] in document d`)
+	if err := i.Save(); err != nil {
+		t.Fatal(err)
+	}
+	run(t, i, `insert [{name:"c"}] in document d`)
+	i.Close()
+
+	i, err = NewInterpreter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer i.Close()
+	run(t, i, "select db shop", "select collection users")
+	if n := countWhere(t, i, `name != "zzz"`); n != 3 {
+		t.Errorf("%d objects after reopening, want 3", n)
+	}
+}
+
+func TestInsertForIsAtomic(t *testing.T) {
+	tests := []struct {
+		name  string
+		inTx  bool
+		lines []string
+	}{
+		{name: "autocommit"},
+		{name: "inside a transaction", inTx: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			i := newTestInterpreter(t)
+			run(t, i, `insert [{name:"a"},{name:"b"}] in document d`)
+			if tc.inTx {
+				run(t, i, "begin", `insert [{name:"c"}] in document d`)
+			}
+
+			// el segundo filtro no encuentra nada: el primero no debe aplicarse
+			if _, err := i.Run(`insert {tag:"x"} for [{name:"a"},{name:"zzz"}] in document d`); err == nil {
+				t.Fatal("insert ... for with an unmatched filter: want error")
+			}
+			if tc.inTx {
+				if !i.InTx() {
+					t.Fatal("failed statement closed the transaction")
+				}
+				run(t, i, "commit")
+			}
+			if n := countWhere(t, i, `tag = "x"`); n != 0 {
+				t.Errorf("%d objects tagged after a failed insert ... for, want 0", n)
+			}
+			if tc.inTx {
+				if n := countWhere(t, i, `name = "c"`); n != 1 {
+					t.Errorf("earlier statement of the transaction lost: %d objects named c", n)
+				}
+			}
+
+			res := run(t, i, `insert {tag:"y"} for [{name:"a"},{name:"b"}] in document d`)[0]
+			if res.Affected != 2 {
+				t.Errorf("insert ... for affected %d objects, want 2", res.Affected)
+			}
+		})
+	}
+}
+
+// limitJournal acepta las primeras n mutaciones y falla con las siguientes.
+type limitJournal struct{ n int }
+
+func (j *limitJournal) Record(engine.Mutation) error {
+	if j.n == 0 {
+		return errors.New("journal full")
+	}
+	j.n--
+	return nil
+}
+
+// Un modify con varios filtros no se queda a medias: con un journal que solo
+// admite una escritura, o se aplican todos los filtros en ella o ninguno.
+func TestModifyIsAtomic(t *testing.T) {
+	i := newTestInterpreter(t)
+	run(t, i, `insert [{name:"a"},{name:"b"}] in document d`)
+	i.idx.SetJournal(&limitJournal{n: 1})
+	res, err := i.Run(`modify {tag:"x"} for [{name:"a"},{name:"b"}] in document d`)
+	i.idx.SetJournal(nil)
+
+	tagged := countWhere(t, i, `tag = "x"`)
+	switch {
+	case err != nil && tagged != 0:
+		t.Errorf("failed modify left %d objects tagged, want 0 (%v)", tagged, err)
+	case err == nil && (tagged != 2 || res.Affected != 2):
+		t.Errorf("modify affected %d and tagged %d objects, want 2", res.Affected, tagged)
+	}
+}
+
+// La clave de una colección se consulta con find where como cualquier campo.
+func TestCollectionKeysQuery(t *testing.T) {
+	i := newTestInterpreter(t)
+	run(t, i, "create collections people with keys objectid", "select collection people",
+		"create documents d", `insert [{name:"a"},{name:"b"}] in document d`)
+
+	objs := run(t, i, `find where name = "b"`)[0].Objects
+	if len(objs) != 1 {
+		t.Fatalf("find name = b: %d objects, want 1", len(objs))
+	}
+	key, ok := objs[0].Fields[db.KeyField].(string)
+	if !ok || key == "" {
+		t.Fatalf("object without %s: %v", db.KeyField, objs[0].Fields)
+	}
+	for _, where := range []string{db.KeyField + ` = "` + key + `"`, db.KeyField + ` = '` + key + `'`} {
+		if got := run(t, i, "find where "+where)[0].Objects; len(got) != 1 || got[0].ID != objs[0].ID {
+			t.Errorf("find where %s = %v, want object %d", where, got, objs[0].ID)
+		}
+	}
+}
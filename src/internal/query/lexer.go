@@ -13,7 +13,7 @@ const (
 	WS
 
 	IDENT    // nombres, comandos (list, select, insert...)
-	STRING   // "cadena entre comillas"
+	STRING   // "cadena entre comillas" o 'simples'
 	NUMBER   // números
 	LBRACE   // {
 	RBRACE   // }
@@ -21,8 +21,18 @@ const (
 	RBRACKET // ]
 	COMMA    // ,
 	COLON    // :
-	EQ       // =
+	EQ       // = o ==
 	ASTERISK // *
+	NEQ      // !=
+	LT       // <
+	LTE      // <=
+	GT       // >
+	GTE      // >=
+	NOT      // !
+	AND      // &&
+	OR       // ||
+	LPAREN   // (
+	RPAREN   // )
 )
 
 type Token struct {
@@ -79,14 +89,60 @@ func (l *Lexer) NextToken() Token {
 		return Token{Type: COLON, Value: ":"}
 	case '=':
 		l.readChar()
+		if l.ch == '=' {
+			l.readChar()
+		}
 		return Token{Type: EQ, Value: "="}
 	case '*':
 		l.readChar()
 		return Token{Type: ASTERISK, Value: "*"}
-	case '"':
-		return l.readString()
+	case '!':
+		l.readChar()
+		if l.ch == '=' {
+			l.readChar()
+			return Token{Type: NEQ, Value: "!="}
+		}
+		return Token{Type: NOT, Value: "!"}
+	case '<':
+		l.readChar()
+		if l.ch == '=' {
+			l.readChar()
+			return Token{Type: LTE, Value: "<="}
+		}
+		return Token{Type: LT, Value: "<"}
+	case '>':
+		l.readChar()
+		if l.ch == '=' {
+			l.readChar()
+			return Token{Type: GTE, Value: ">="}
+		}
+		return Token{Type: GT, Value: ">"}
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			l.readChar()
+			return Token{Type: AND, Value: "&&"}
+		}
+		l.readChar()
+		return Token{Type: ILLEGAL, Value: "&"}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			l.readChar()
+			return Token{Type: OR, Value: "||"}
+		}
+		l.readChar()
+		return Token{Type: ILLEGAL, Value: "|"}
+	case '(':
+		l.readChar()
+		return Token{Type: LPAREN, Value: "("}
+	case ')':
+		l.readChar()
+		return Token{Type: RPAREN, Value: ")"}
+	case '"', '\'':
+		return l.readString(l.ch)
 	default:
-		if isLetter(l.ch) {
+		if isLetter(l.ch) || l.ch == '_' {
 			return l.readIdentifier()
 		} else if isDigit(l.ch) || (l.ch == '-' && isDigit(l.peekChar())) {
 			return l.readNumber()
@@ -113,14 +169,16 @@ func (l *Lexer) readIdentifier() Token {
 	return Token{Type: IDENT, Value: strings.ToLower(val)} // lowercase para simplificar
 }
 
-func (l *Lexer) readString() Token {
-	l.readChar() // skip "
+// readString lee una cadena hasta la comilla que la cierra, que es la misma con
+// la que empieza (' o "), así que cada una puede contener la otra.
+func (l *Lexer) readString(quote rune) Token {
+	l.readChar() // skip quote
 	pos := l.position
-	for l.ch != '"' && l.ch != 0 {
+	for l.ch != quote && l.ch != 0 {
 		l.readChar()
 	}
 	val := l.input[pos:l.position]
-	l.readChar() // skip "
+	l.readChar() // skip quote
 	return Token{Type: STRING, Value: val}
 }
 
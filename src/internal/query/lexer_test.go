@@ -0,0 +1,58 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+
+	db "machDB/src/internal/db"
+)
+
+func TestLexer(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []Token
+	}{
+		{`"Lima"`, []Token{{STRING, "Lima"}}},
+		{`'Lima'`, []Token{{STRING, "Lima"}}},
+		{`'San Isidro'`, []Token{{STRING, "San Isidro"}}},
+		{`''`, []Token{{STRING, ""}}},
+		{`'dijo "hola"'`, []Token{{STRING, `dijo "hola"`}}},
+		{`"O'Brien"`, []Token{{STRING, "O'Brien"}}},
+		{`_key = 'a1'`, []Token{{IDENT, "_key"}, {EQ, "="}, {STRING, "a1"}}},
+		{`city = 'Lima'`, []Token{{IDENT, "city"}, {EQ, "="}, {STRING, "Lima"}}},
+		{`age >= 18 and city = 'Lima'`, []Token{
+			{IDENT, "age"}, {GTE, ">="}, {NUMBER, "18"}, {IDENT, "and"},
+			{IDENT, "city"}, {EQ, "="}, {STRING, "Lima"},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			l := NewLexer(tc.input)
+			var got []Token
+			for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+				got = append(got, tok)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("tokens = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// El ejemplo de la documentación se evalúa igual con comillas simples y dobles.
+func TestParseWhereQuotes(t *testing.T) {
+	lima := &db.Object{Fields: map[string]interface{}{"age": 30.0, "city": "Lima"}}
+	cusco := &db.Object{Fields: map[string]interface{}{"age": 30.0, "city": "Cusco"}}
+	for _, src := range []string{
+		`age >= 18 and city = 'Lima'`,
+		`age >= 18 and city = "Lima"`,
+	} {
+		f, err := ParseWhere(src)
+		if err != nil {
+			t.Fatalf("ParseWhere(%s): %v", src, err)
+		}
+		if !f.Match(lima) || f.Match(cusco) {
+			t.Errorf("ParseWhere(%s) matches Lima %v, Cusco %v; want true, false", src, f.Match(lima), f.Match(cusco))
+		}
+	}
+}
@@ -14,6 +14,8 @@ type Command struct {
 	Properties []map[string]interface{} // para insert/modify JSON-like
 	Filters    []map[string]interface{} // para where / for
 	RawQuery   []string                 // para find con varios filtros
+	Where      Expr                     // para find where <expr>
+	Join       string                   // para find join: inner, left, right u outer
 }
 
 // Parser estructura principal
@@ -86,6 +88,11 @@ func (p *Parser) ParseCommand() (*Command, error) {
 		if err := p.parseExport(cmd); err != nil {
 			return nil, err
 		}
+	case "begin", "commit", "rollback":
+		// sin argumentos
+		if p.curToken.Type != EOF {
+			return nil, fmt.Errorf("%s takes no arguments, got %s", cmd.Name, p.curToken.Value)
+		}
 	default:
 		return nil, fmt.Errorf("unknown command %s", cmd.Name)
 	}
@@ -140,6 +147,7 @@ func (p *Parser) parseSelect(cmd *Command) error {
 func (p *Parser) parseCreate(cmd *Command) error {
 	// create document name_document
 	// create collection name_collection
+	// create collection name_collection with keys objectid|uuid
 	if p.curToken.Type != IDENT {
 		return errors.New("expected 'document' or 'collection' after create")
 	}
@@ -151,6 +159,19 @@ func (p *Parser) parseCreate(cmd *Command) error {
 	}
 	cmd.Args = append(cmd.Args, p.curToken.Value)
 	p.nextToken()
+
+	if p.curToken.Type == IDENT && p.curToken.Value == "with" {
+		p.nextToken()
+		if p.curToken.Type != IDENT || p.curToken.Value != "keys" {
+			return errors.New("expected 'keys' after with")
+		}
+		p.nextToken()
+		if p.curToken.Type != IDENT {
+			return errors.New("expected key kind (objectid, uuid) after with keys")
+		}
+		cmd.Args = append(cmd.Args, p.curToken.Value)
+		p.nextToken()
+	}
 	return nil
 }
 
@@ -261,11 +282,45 @@ func (p *Parser) parseFind(cmd *Command) error {
 	// find "name:luis"
 	// find "name:Luis" in NameCollection
 	// find "name:Luis" "city:New york" ventas users_address
+	// find where age >= 18 and (city = "Lima" or city = "Bogotá") in users
+	// find left join "users.id=orders.user_id" ventas users orders
+
+	if p.curToken.Type == IDENT && p.peekToken.Type == IDENT && p.peekToken.Value == "join" {
+		switch p.curToken.Value {
+		case "inner", "left", "right", "outer":
+			cmd.Join = p.curToken.Value
+			p.nextToken()
+		}
+	}
+	if p.curToken.Type == IDENT && p.curToken.Value == "join" {
+		if cmd.Join == "" {
+			cmd.Join = "inner"
+		}
+		p.nextToken()
+		if p.curToken.Type != STRING {
+			return errors.New("expected join condition after 'join'")
+		}
+	}
+
+	if p.curToken.Type == IDENT && p.curToken.Value == "where" {
+		p.nextToken()
+		expr, err := p.parseOr()
+		if err != nil {
+			return err
+		}
+		cmd.Where = expr
+		if p.curToken.Type == STRING {
+			return errors.New("cannot mix where with quoted queries in find")
+		}
+	}
 
 	for p.curToken.Type == STRING {
 		cmd.RawQuery = append(cmd.RawQuery, p.curToken.Value)
 		p.nextToken()
 	}
+	if len(cmd.RawQuery) > 0 && cmd.Join == "" && p.curToken.Type == IDENT && p.curToken.Value == "where" {
+		return errors.New("cannot mix where with quoted queries in find")
+	}
 
 	// opcional: "in" collectionName
 	if p.curToken.Type == IDENT && p.curToken.Value == "in" {
@@ -296,13 +351,22 @@ func (p *Parser) parseImport(cmd *Command) error {
 }
 
 func (p *Parser) parseExport(cmd *Command) error {
-	// export db/collection/document filename_path
+	// export db/collection filename_path
+	// export document nameDocument filename_path
 	if p.curToken.Type != IDENT {
 		return errors.New("expected db/collection/document after export")
 	}
 	cmd.Args = append(cmd.Args, p.curToken.Value)
 	p.nextToken()
 
+	if cmd.Args[0] == "document" || cmd.Args[0] == "documents" {
+		if p.curToken.Type != IDENT {
+			return errors.New("expected document name after export document")
+		}
+		cmd.Args = append(cmd.Args, p.curToken.Value)
+		p.nextToken()
+	}
+
 	if p.curToken.Type != IDENT && p.curToken.Type != STRING {
 		return errors.New("expected filename after export target")
 	}
@@ -311,6 +375,104 @@ func (p *Parser) parseExport(cmd *Command) error {
 	return nil
 }
 
+// Gramática del filtro de find where, de menor a mayor precedencia:
+//
+//	or      := and (("or" | "||") and)*
+//	and     := unary (("and" | "&&") unary)*
+//	unary   := ("not" | "!") unary | primary
+//	primary := "(" or ")" | campo op valor
+//	op      := = | == | != | < | <= | > | >=
+
+func (p *Parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.curToken.Type == OR || (p.curToken.Type == IDENT && p.curToken.Value == "or") {
+		p.nextToken()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *Parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.curToken.Type == AND || (p.curToken.Type == IDENT && p.curToken.Value == "and") {
+		p.nextToken()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *Parser) parseUnary() (Expr, error) {
+	if p.curToken.Type == NOT || (p.curToken.Type == IDENT && p.curToken.Value == "not") {
+		p.nextToken()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{Expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *Parser) parsePrimary() (Expr, error) {
+	if p.curToken.Type == LPAREN {
+		p.nextToken()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.curToken.Type != RPAREN {
+			return nil, errors.New("expected ')' in where expression")
+		}
+		p.nextToken()
+		return expr, nil
+	}
+
+	if p.curToken.Type != IDENT && p.curToken.Type != STRING {
+		return nil, fmt.Errorf("expected field name in where expression, got %q", p.curToken.Value)
+	}
+	field := p.curToken.Value
+	p.nextToken()
+
+	var op string
+	switch p.curToken.Type {
+	case EQ, COLON:
+		op = "="
+	case NEQ:
+		op = "!="
+	case LT:
+		op = "<"
+	case LTE:
+		op = "<="
+	case GT:
+		op = ">"
+	case GTE:
+		op = ">="
+	default:
+		return nil, fmt.Errorf("expected comparison operator after %s", field)
+	}
+	p.nextToken()
+
+	val, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	return &CompareExpr{Field: field, Op: op, Value: val}, nil
+}
+
 // parseProps parsea estructuras tipo JSON simples {key:value,...} o listas [{...},{...}]
 func (p *Parser) parseProps() ([]map[string]interface{}, error) {
 	if p.curToken.Type == LBRACKET {
@@ -0,0 +1,28 @@
+package query
+
+import db "machDB/src/internal/db"
+
+// Result es lo que devuelve Execute. Cada comando rellena solo lo suyo y quien
+// llama (el REPL, un servidor, un test) decide cómo mostrarlo:
+//
+//	list                 Names
+//	select               Message (y CurrentDB/CurrentColl del Interpreter)
+//	create, delete       Affected = 1
+//	insert               IDs de los objetos nuevos o actualizados, Affected = len(IDs)
+//	modify               Affected
+//	find                 Objects (vacío si no hay coincidencias)
+//	find ... join        Rows, con claves "coleccion.campo"
+//	import               Affected = objetos importados, Errors por fila
+//	export               Affected = objetos exportados
+//	begin, rollback      Message
+//	commit               Affected = operaciones aplicadas
+type Result struct {
+	Command  string
+	Names    []string
+	Objects  []*db.Object
+	Rows     []map[string]interface{}
+	IDs      []int
+	Affected int
+	Message  string
+	Errors   []string
+}
@@ -0,0 +1,384 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	db "machDB/src/internal/db"
+	"machDB/src/internal/engine"
+	"machDB/src/internal/query"
+	"machDB/src/internal/storage"
+)
+
+// maxBodySize limita el cuerpo de cada petición (un insert masivo cabe de sobra).
+const maxBodySize = 32 << 20
+
+// Server expone el Engine por HTTP con cuerpos JSON:
+//
+//	GET    /databases                                         lista de bases de datos
+//	POST   /databases                                         {"name": "ventas"}
+//	DELETE /databases/{db}
+//	GET    /databases/{db}/collections
+//	POST   /databases/{db}/collections                        {"name": "users"}
+//	DELETE /databases/{db}/collections/{col}
+//	GET    /databases/{db}/collections/{col}/documents
+//	POST   /databases/{db}/collections/{col}/documents        {"name": "clientes"}
+//	DELETE /databases/{db}/collections/{col}/documents/{doc}
+//	GET    /databases/{db}/collections/{col}/documents/{doc}/objects
+//	POST   /databases/{db}/collections/{col}/documents/{doc}/objects   objeto o array de objetos
+//	POST   /databases/{db}/find                               ver findRequest
+//	GET    /databases/{db}/export?format=json&collection=&document=
+//
+// Los errores se devuelven como {"error": "..."}: 404 si algo no existe, 409 si ya
+// existe, 400 si la petición o la consulta no son válidas y 413 si el cuerpo
+// pasa de maxBodySize.
+type Server struct {
+	engine *engine.Engine
+	mux    *http.ServeMux
+}
+
+// New crea el servidor sobre e. La persistencia (WAL, snapshot) es cosa de quien
+// creó e: el servidor solo llama a sus métodos.
+func New(e *engine.Engine) *Server {
+	s := &Server{engine: e, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /databases", s.listDatabases)
+	s.mux.HandleFunc("POST /databases", s.createDatabase)
+	s.mux.HandleFunc("DELETE /databases/{db}", s.deleteDatabase)
+
+	s.mux.HandleFunc("GET /databases/{db}/collections", s.listCollections)
+	s.mux.HandleFunc("POST /databases/{db}/collections", s.createCollection)
+	s.mux.HandleFunc("DELETE /databases/{db}/collections/{col}", s.deleteCollection)
+
+	s.mux.HandleFunc("GET /databases/{db}/collections/{col}/documents", s.listDocuments)
+	s.mux.HandleFunc("POST /databases/{db}/collections/{col}/documents", s.createDocument)
+	s.mux.HandleFunc("DELETE /databases/{db}/collections/{col}/documents/{doc}", s.deleteDocument)
+
+	s.mux.HandleFunc("GET /databases/{db}/collections/{col}/documents/{doc}/objects", s.listObjects)
+	s.mux.HandleFunc("POST /databases/{db}/collections/{col}/documents/{doc}/objects", s.insertObjects)
+
+	s.mux.HandleFunc("POST /databases/{db}/find", s.find)
+	s.mux.HandleFunc("GET /databases/{db}/export", s.export)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type nameRequest struct {
+	Name string `json:"name"`
+}
+
+// findRequest admite las tres formas de find del CLI; se usa la primera presente:
+//
+//	{"where": "age >= 18 and city = 'Lima'"}
+//	{"queries": ["age>18", "city:Lima"]}
+//	{"field": "city", "value": "Lima"}
+//
+// collections restringe la búsqueda; vacío busca en toda la base de datos.
+type findRequest struct {
+	Where       string      `json:"where"`
+	Queries     []string    `json:"queries"`
+	Field       string      `json:"field"`
+	Value       interface{} `json:"value"`
+	Collections []string    `json:"collections"`
+}
+
+func (s *Server) listDatabases(w http.ResponseWriter, r *http.Request) {
+	names := s.engine.ListDatabases()
+	sort.Strings(names)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"databases": names})
+}
+
+func (s *Server) createDatabase(w http.ResponseWriter, r *http.Request) {
+	name, err := decodeName(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.engine.CreateDatabase(name); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"name": name})
+}
+
+func (s *Server) deleteDatabase(w http.ResponseWriter, r *http.Request) {
+	if err := s.engine.DeleteDatabase(r.PathValue("db")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listCollections(w http.ResponseWriter, r *http.Request) {
+	names, err := s.engine.ListCollections(r.PathValue("db"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sort.Strings(names)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"collections": names})
+}
+
+func (s *Server) createCollection(w http.ResponseWriter, r *http.Request) {
+	name, err := decodeName(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.engine.CreateCollection(r.PathValue("db"), name); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"name": name})
+}
+
+func (s *Server) deleteCollection(w http.ResponseWriter, r *http.Request) {
+	if err := s.engine.DeleteCollection(r.PathValue("db"), r.PathValue("col")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listDocuments(w http.ResponseWriter, r *http.Request) {
+	names, err := s.engine.ListDocuments(r.PathValue("db"), r.PathValue("col"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sort.Strings(names)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"documents": names})
+}
+
+func (s *Server) createDocument(w http.ResponseWriter, r *http.Request) {
+	name, err := decodeName(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.engine.CreateDocument(r.PathValue("db"), r.PathValue("col"), name); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"name": name})
+}
+
+func (s *Server) deleteDocument(w http.ResponseWriter, r *http.Request) {
+	if err := s.engine.DeleteDocument(r.PathValue("db"), r.PathValue("col"), r.PathValue("doc")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listObjects(w http.ResponseWriter, r *http.Request) {
+	objs, err := s.engine.ListObjects(r.PathValue("db"), r.PathValue("col"), r.PathValue("doc"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"objects": objs})
+}
+
+// insertObjects acepta un objeto ({"name": "Luis"}) o un array de objetos y
+// responde con los IDs asignados, en orden.
+func (s *Server) insertObjects(w http.ResponseWriter, r *http.Request) {
+	var body interface{}
+	if err := decodeBody(w, r, &body); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var objs []map[string]interface{}
+	switch tv := body.(type) {
+	case map[string]interface{}:
+		objs = append(objs, tv)
+	case []interface{}:
+		for n, item := range tv {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				writeError(w, badRequest(fmt.Errorf("element %d is not an object", n)))
+				return
+			}
+			objs = append(objs, obj)
+		}
+	default:
+		writeError(w, badRequest(errors.New("expected an object or an array of objects")))
+		return
+	}
+	if len(objs) == 0 {
+		writeError(w, badRequest(errors.New("no objects to insert")))
+		return
+	}
+
+	dbName, colName, docName := r.PathValue("db"), r.PathValue("col"), r.PathValue("doc")
+	var ids []int
+	var err error
+	if len(objs) == 1 {
+		var oid int
+		oid, err = s.engine.InsertObject(dbName, colName, docName, objs[0])
+		ids = []int{oid}
+	} else {
+		ids, err = s.engine.InsertObjects(dbName, colName, docName, objs)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"ids": ids})
+}
+
+// find responde siempre {"objects": [...]}; sin coincidencias la lista va vacía.
+func (s *Server) find(w http.ResponseWriter, r *http.Request) {
+	var req findRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	req.Value = db.NormalizeNumber(req.Value)
+
+	dbName := r.PathValue("db")
+	var objs []*db.Object
+	var err error
+	switch {
+	case req.Where != "":
+		var f engine.Filter
+		if f, err = query.ParseWhere(req.Where); err == nil {
+			objs, err = s.engine.FindWhere(f, dbName, req.Collections...)
+		}
+	case len(req.Queries) > 0:
+		objs, err = s.engine.FindByQueries(req.Queries, dbName, req.Collections...)
+	case req.Field != "":
+		objs, err = s.engine.Find(req.Field, req.Value, dbName, req.Collections...)
+	default:
+		err = fmt.Errorf("%w: expected where, queries or field", engine.ErrBadQuery)
+	}
+	if errors.Is(err, engine.ErrNoResults) {
+		if _, lerr := s.engine.ListCollections(dbName); lerr != nil {
+			err = lerr
+		} else {
+			objs, err = []*db.Object{}, nil
+		}
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"objects": objs})
+}
+
+var exportContentTypes = map[string]string{
+	storage.FormatJSON:   "application/json",
+	storage.FormatNDJSON: "application/x-ndjson",
+	storage.FormatCSV:    "text/csv; charset=utf-8",
+}
+
+// export devuelve la base de datos (o la colección/documento de la query) con el
+// formato pedido, json por defecto. Es lo mismo que escribe el export del CLI.
+func (s *Server) export(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = storage.FormatJSON
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		writeError(w, badRequest(fmt.Errorf("unknown export format %s (use json, ndjson or csv)", format)))
+		return
+	}
+	scope := storage.Scope{DB: r.PathValue("db"), Collection: q.Get("collection"), Document: q.Get("document")}
+	if scope.Document != "" && scope.Collection == "" {
+		writeError(w, badRequest(errors.New("document export needs a collection")))
+		return
+	}
+
+	var buf bytes.Buffer
+	if _, err := storage.WriteExport(s.engine, scope, format, &buf); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// requestError marca errores del cliente (JSON mal formado, cuerpo inválido).
+type requestError struct {
+	err error
+}
+
+func (e requestError) Error() string { return e.err.Error() }
+func (e requestError) Unwrap() error { return e.err }
+
+func badRequest(err error) error {
+	return requestError{err: err}
+}
+
+// decodeBody lee el cuerpo JSON con UseNumber para que los enteros lleguen al
+// engine como int, igual que desde el CLI o el disco. Un cuerpo de más de
+// maxBodySize da un *http.MaxBytesError (413) y w cierra la conexión.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		return badRequest(err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return badRequest(fmt.Errorf("invalid JSON body: %w", err))
+	}
+	if dec.More() {
+		return badRequest(errors.New("invalid JSON body: trailing data"))
+	}
+	if p, ok := v.(*interface{}); ok {
+		*p = db.NormalizeNumber(*p)
+	}
+	return nil
+}
+
+func decodeName(w http.ResponseWriter, r *http.Request) (string, error) {
+	var req nameRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		return "", err
+	}
+	if req.Name == "" {
+		return "", badRequest(errors.New("name is required"))
+	}
+	return req.Name, nil
+}
+
+// statusOf traduce los errores del engine a códigos HTTP.
+func statusOf(err error) int {
+	var reqErr requestError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, &reqErr), errors.Is(err, engine.ErrBadQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrAlreadyExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, statusOf(err), map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"machDB/src/internal/engine"
+)
+
+// testServer: shop/users/a con dos objetos, detrás de un Server.
+func testServer(t *testing.T) *Server {
+	t.Helper()
+	e := engine.NewEngine()
+	steps := []error{
+		e.CreateDatabase("shop"),
+		e.CreateCollection("shop", "users"),
+		e.CreateDocument("shop", "users", "a"),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	objs := []map[string]interface{}{{"name": "ana", "age": 30}, {"name": "bob", "age": 17}}
+	if _, err := e.InsertObjects("shop", "users", "a", objs); err != nil {
+		t.Fatal(err)
+	}
+	return New(e)
+}
+
+// do hace la petición contra s y devuelve la respuesta grabada.
+func do(s *Server, method, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+// Cada ruta con una petición válida: código y cuerpo (JSON compacto, sin el
+// salto de línea final).
+func TestRoutes(t *testing.T) {
+	const doc = "/databases/shop/collections/users/documents/a"
+	tests := []struct {
+		method, path, body string
+		status             int
+		want               string
+	}{
+		{"GET", "/databases", "", 200, `{"databases":["shop"]}`},
+		{"POST", "/databases", `{"name":"tmp"}`, 201, `{"name":"tmp"}`},
+		{"DELETE", "/databases/tmp", "", 204, ``},
+		{"GET", "/databases/shop/collections", "", 200, `{"collections":["users"]}`},
+		{"POST", "/databases/shop/collections", `{"name":"orders"}`, 201, `{"name":"orders"}`},
+		{"DELETE", "/databases/shop/collections/orders", "", 204, ``},
+		{"GET", "/databases/shop/collections/users/documents", "", 200, `{"documents":["a"]}`},
+		{"POST", "/databases/shop/collections/users/documents", `{"name":"b"}`, 201, `{"name":"b"}`},
+		{"DELETE", "/databases/shop/collections/users/documents/b", "", 204, ``},
+		{"GET", doc + "/objects", "", 200, `{"objects":[{"id":0,"fields":{"age":30,"name":"ana"}},{"id":1,"fields":{"age":17,"name":"bob"}}]}`},
+		{"POST", doc + "/objects", `{"name":"cid","age":41}`, 201, `{"ids":[2]}`},
+		{"POST", doc + "/objects", `[{"name":"dan"},{"name":"eva"}]`, 201, `{"ids":[3,4]}`},
+		{"POST", "/databases/shop/find", `{"where":"age >= 18 and name != 'cid'"}`, 200, `{"objects":[{"id":0,"fields":{"age":30,"name":"ana"}}]}`},
+		{"POST", "/databases/shop/find", `{"queries":["age<18"]}`, 200, `{"objects":[{"id":1,"fields":{"age":17,"name":"bob"}}]}`},
+		{"POST", "/databases/shop/find", `{"field":"age","value":41,"collections":["users"]}`, 200, `{"objects":[{"id":2,"fields":{"age":41,"name":"cid"}}]}`},
+		{"POST", "/databases/shop/find", `{"field":"name","value":"zoe"}`, 200, `{"objects":[]}`},
+		{"GET", "/databases/shop/export?format=ndjson&collection=users&document=a", "", 200, `{"collection":"users","document":"a","id":0,"fields":{"age":30,"name":"ana"}}`},
+	}
+	s := testServer(t)
+	for _, tc := range tests {
+		rec := do(s, tc.method, tc.path, tc.body)
+		if rec.Code != tc.status {
+			t.Errorf("%s %s: status %d, want %d (%s)", tc.method, tc.path, rec.Code, tc.status, rec.Body)
+			continue
+		}
+		// el export ndjson trae una línea por objeto: basta con la primera
+		got, _, _ := strings.Cut(rec.Body.String(), "\n")
+		if got != tc.want {
+			t.Errorf("%s %s: body %s, want %s", tc.method, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestExportContentType(t *testing.T) {
+	s := testServer(t)
+	tests := map[string]string{
+		"":       "application/json",
+		"json":   "application/json",
+		"ndjson": "application/x-ndjson",
+		"csv":    "text/csv; charset=utf-8",
+	}
+	for format, want := range tests {
+		rec := do(s, "GET", "/databases/shop/export?format="+format, "")
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != want {
+			t.Errorf("format %q: status %d, Content-Type %q; want 200 and %q", format, rec.Code, rec.Header().Get("Content-Type"), want)
+		}
+	}
+}
+
+// Los errores del engine y de la petición llegan como {"error": ...} con su código.
+func TestErrorStatus(t *testing.T) {
+	const doc = "/databases/shop/collections/users/documents/a"
+	tests := []struct {
+		name, method, path, body string
+		status                   int
+	}{
+		{"missing database", "GET", "/databases/nope/collections", "", http.StatusNotFound},
+		{"missing collection", "DELETE", "/databases/shop/collections/nope", "", http.StatusNotFound},
+		{"missing document", "GET", "/databases/shop/collections/users/documents/nope/objects", "", http.StatusNotFound},
+		{"insert into a missing document", "POST", "/databases/shop/collections/users/documents/nope/objects", `{"a":1}`, http.StatusNotFound},
+		{"find in a missing database", "POST", "/databases/nope/find", `{"field":"a","value":1}`, http.StatusNotFound},
+		{"export a missing collection", "GET", "/databases/shop/export?collection=nope", "", http.StatusNotFound},
+		{"database exists", "POST", "/databases", `{"name":"shop"}`, http.StatusConflict},
+		{"collection exists", "POST", "/databases/shop/collections", `{"name":"users"}`, http.StatusConflict},
+		{"document exists", "POST", "/databases/shop/collections/users/documents", `{"name":"a"}`, http.StatusConflict},
+		{"bad where", "POST", "/databases/shop/find", `{"where":"age >="}`, http.StatusBadRequest},
+		{"bad query", "POST", "/databases/shop/find", `{"queries":["age"]}`, http.StatusBadRequest},
+		{"empty find", "POST", "/databases/shop/find", `{}`, http.StatusBadRequest},
+		{"bad JSON", "POST", "/databases", `{"name":`, http.StatusBadRequest},
+		{"trailing data", "POST", "/databases", `{"name":"x"} {}`, http.StatusBadRequest},
+		{"no name", "POST", "/databases", `{}`, http.StatusBadRequest},
+		{"not an object", "POST", doc + "/objects", `[1]`, http.StatusBadRequest},
+		{"nothing to insert", "POST", doc + "/objects", `[]`, http.StatusBadRequest},
+		{"unknown export format", "GET", "/databases/shop/export?format=xml", "", http.StatusBadRequest},
+		{"document without collection", "GET", "/databases/shop/export?document=a", "", http.StatusBadRequest},
+		{"body too large", "POST", doc + "/objects", `{"a":"` + strings.Repeat("x", maxBodySize) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	s := testServer(t)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := do(s, tc.method, tc.path, tc.body)
+			if rec.Code != tc.status {
+				t.Errorf("status %d, want %d (%s)", rec.Code, tc.status, rec.Body)
+			}
+			var body map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] == "" {
+				t.Errorf("body %q, want {\"error\": ...}", rec.Body)
+			}
+		})
+	}
+
+	// nada de lo anterior cambió los datos
+	rec := do(s, "GET", doc+"/objects", "")
+	var got struct{ Objects []json.RawMessage }
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got.Objects) != 2 {
+		t.Errorf("objects after the errors: %s", rec.Body)
+	}
+}
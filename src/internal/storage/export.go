@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	db "machDB/src/internal/db"
+	"machDB/src/internal/engine"
+)
+
+// Formatos de export/import, elegidos por la extensión del archivo.
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// FormatFromPath deduce el formato de la extensión: .json, .ndjson/.jsonl o .csv.
+func FormatFromPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, nil
+	case ".csv":
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("unknown export format for %s (use .json, .ndjson or .csv)", path)
+	}
+}
+
+// Scope indica qué exportar: DB sola exporta la base de datos, DB+Collection una
+// colección y DB+Collection+Document un único documento.
+type Scope struct {
+	DB         string
+	Collection string
+	Document   string
+}
+
+// Export es el formato JSON de export: conserva la jerarquía del Scope exportado.
+type Export struct {
+	Database    string                       `json:"database"`
+	Collection  string                       `json:"collection,omitempty"`
+	Document    string                       `json:"document,omitempty"`
+	Collections map[string]*ExportCollection `json:"collections,omitempty"`
+	Documents   map[string][]*db.Object      `json:"documents,omitempty"`
+	Objects     []*db.Object                 `json:"objects,omitempty"`
+}
+
+type ExportCollection struct {
+	Documents map[string][]*db.Object `json:"documents"`
+}
+
+// Row es un objeto con su ubicación; es la línea de NDJSON y la fila de CSV.
+type Row struct {
+	Collection string                 `json:"collection"`
+	Document   string                 `json:"document"`
+	ID         int                    `json:"id"`
+	Fields     map[string]interface{} `json:"fields"`
+}
+
+// Columnas fijas del CSV, antes de la unión de campos. El ID entero va en _oid
+// para no confundirse con un campo del objeto (db.KeyField incluido).
+var csvMetaColumns = []string{"_collection", "_document", "_oid"}
+
+// ExportTo escribe scope en path con el formato de su extensión y devuelve
+// cuántos objetos se exportaron.
+func ExportTo(e *engine.Engine, scope Scope, path string) (int, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return 0, err
+	}
+
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+
+	n, err := WriteExport(e, scope, format, w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, err
+	}
+	return n, nil
+}
+
+// WriteExport escribe scope en w con format (FormatJSON, FormatNDJSON o
+// FormatCSV) y devuelve cuántos objetos se exportaron. Trabaja sobre un
+// Engine.Snapshot de la base de datos, así que escribir no bloquea al engine.
+func WriteExport(e *engine.Engine, scope Scope, format string, w io.Writer) (int, error) {
+	return export(w, format, e.Snapshot(nil, scope.DB), scope)
+}
+
+func export(w io.Writer, format string, databases map[string]*db.Database, scope Scope) (int, error) {
+	rows, err := collectRows(databases, scope)
+	if err != nil {
+		return 0, err
+	}
+
+	switch format {
+	case FormatJSON:
+		out := buildExport(rows, scope)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return len(rows), enc.Encode(out)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, r := range rows {
+			if err := enc.Encode(r); err != nil {
+				return 0, err
+			}
+		}
+		return len(rows), nil
+	case FormatCSV:
+		return len(rows), writeCSV(w, rows)
+	default:
+		return 0, fmt.Errorf("unknown export format %s", format)
+	}
+}
+
+// collectRows recorre el scope en orden estable (colecciones y documentos por nombre,
+// objetos en el orden del documento).
+func collectRows(databases map[string]*db.Database, scope Scope) ([]Row, error) {
+	database, ok := databases[scope.DB]
+	if !ok {
+		return nil, fmt.Errorf("database %s %w", scope.DB, db.ErrNotFound)
+	}
+
+	colNames := sortedNames(database.Collections)
+	if scope.Collection != "" {
+		if _, err := database.GetCollection(scope.Collection); err != nil {
+			return nil, err
+		}
+		colNames = []string{scope.Collection}
+	}
+
+	var rows []Row
+	for _, colName := range colNames {
+		col := database.Collections[colName]
+		docNames := sortedNames(col.Documents)
+		if scope.Document != "" {
+			if _, err := col.GetDocument(scope.Document); err != nil {
+				return nil, err
+			}
+			docNames = []string{scope.Document}
+		}
+		for _, docName := range docNames {
+			for _, obj := range col.Documents[docName].Objects {
+				rows = append(rows, Row{Collection: colName, Document: docName, ID: obj.ID, Fields: obj.Fields})
+			}
+		}
+	}
+	return rows, nil
+}
+
+func buildExport(rows []Row, scope Scope) *Export {
+	out := &Export{Database: scope.DB, Collection: scope.Collection, Document: scope.Document}
+	switch {
+	case scope.Document != "":
+		out.Objects = []*db.Object{}
+	case scope.Collection != "":
+		out.Documents = make(map[string][]*db.Object)
+	default:
+		out.Collections = make(map[string]*ExportCollection)
+	}
+
+	for _, r := range rows {
+		obj := db.NewObject(r.ID, r.Fields)
+		switch {
+		case out.Objects != nil:
+			out.Objects = append(out.Objects, obj)
+		case out.Documents != nil:
+			out.Documents[r.Document] = append(out.Documents[r.Document], obj)
+		default:
+			col, ok := out.Collections[r.Collection]
+			if !ok {
+				col = &ExportCollection{Documents: make(map[string][]*db.Object)}
+				out.Collections[r.Collection] = col
+			}
+			col.Documents[r.Document] = append(col.Documents[r.Document], obj)
+		}
+	}
+	return out
+}
+
+// writeCSV aplana Fields con la unión ordenada de campos como cabecera. Los valores
+// anidados se escriben como JSON y los ausentes o null como celda vacía.
+func writeCSV(w io.Writer, rows []Row) error {
+	seen := make(map[string]bool)
+	var fields []string
+	for _, r := range rows {
+		for k := range r.Fields {
+			if !seen[k] {
+				seen[k] = true
+				fields = append(fields, k)
+			}
+		}
+	}
+	sort.Strings(fields)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append(append([]string{}, csvMetaColumns...), fields...)); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		record := []string{r.Collection, r.Document, strconv.Itoa(r.ID)}
+		for _, k := range fields {
+			cell, err := csvCell(r.Fields[k])
+			if err != nil {
+				return err
+			}
+			record = append(record, cell)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvCell(v interface{}) (string, error) {
+	switch tv := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return tv, nil
+	case bool, int, int64, float64:
+		return fmt.Sprintf("%v", tv), nil
+	default:
+		b, err := json.Marshal(tv)
+		return string(b), err
+	}
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	db "machDB/src/internal/db"
+	"machDB/src/internal/engine"
+)
+
+// exportEngine: shop/users/{a,b} y shop/orders/o con valores de varios tipos.
+func exportEngine(t *testing.T) *engine.Engine {
+	return newTestEngine(t, "shop", nil, docs{
+		"users/a": {
+			{"name": "ana", "age": 30, "vip": true},
+			{"name": "bob, jr", "score": 1.5, "tags": []interface{}{"x", 2}},
+		},
+		"users/b":  {{"name": "cid", "address": map[string]interface{}{"city": "Lima"}}},
+		"orders/o": {{"user": "ana", "total": 12}},
+	})
+}
+
+// exportString exporta scope con format a un string.
+func exportString(t *testing.T, e *engine.Engine, scope Scope, format string) (string, int) {
+	t.Helper()
+	var buf strings.Builder
+	n, err := WriteExport(e, scope, format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.String(), n
+}
+
+func TestExportJSONScopes(t *testing.T) {
+	e := exportEngine(t)
+	tests := []struct {
+		scope Scope
+		n     int
+		want  string // estructura: colecciones/documentos -> número de objetos
+	}{
+		{Scope{DB: "shop"}, 4, "orders/o=1 users/a=2 users/b=1"},
+		{Scope{DB: "shop", Collection: "users"}, 3, "a=2 b=1"},
+		{Scope{DB: "shop", Collection: "users", Document: "a"}, 2, "2"},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%+v", tc.scope), func(t *testing.T) {
+			out, n := exportString(t, e, tc.scope, FormatJSON)
+			if n != tc.n {
+				t.Errorf("exported %d objects, want %d", n, tc.n)
+			}
+			var exp Export
+			if err := json.Unmarshal([]byte(out), &exp); err != nil {
+				t.Fatal(err)
+			}
+			if exp.Database != "shop" || exp.Collection != tc.scope.Collection || exp.Document != tc.scope.Document {
+				t.Errorf("export scope = %s/%s/%s, want %+v", exp.Database, exp.Collection, exp.Document, tc.scope)
+			}
+			var shape []string
+			switch {
+			case exp.Objects != nil:
+				shape = append(shape, fmt.Sprint(len(exp.Objects)))
+			case exp.Documents != nil:
+				for _, doc := range sortedNames(exp.Documents) {
+					shape = append(shape, fmt.Sprintf("%s=%d", doc, len(exp.Documents[doc])))
+				}
+			default:
+				for _, col := range sortedNames(exp.Collections) {
+					for _, doc := range sortedNames(exp.Collections[col].Documents) {
+						shape = append(shape, fmt.Sprintf("%s/%s=%d", col, doc, len(exp.Collections[col].Documents[doc])))
+					}
+				}
+			}
+			if got := strings.Join(shape, " "); got != tc.want {
+				t.Errorf("export holds %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExportNDJSONAndCSV(t *testing.T) {
+	e := exportEngine(t)
+	scope := Scope{DB: "shop", Collection: "users"}
+
+	out, _ := exportString(t, e, scope, FormatNDJSON)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("ndjson has %d lines, want 3:\n%s", len(lines), out)
+	}
+	if want := `{"collection":"users","document":"a","id":0,"fields":{"age":30,"name":"ana","vip":true}}`; lines[0] != want {
+		t.Errorf("first ndjson line = %s, want %s", lines[0], want)
+	}
+
+	out, _ = exportString(t, e, scope, FormatCSV)
+	want := `_collection,_document,_oid,address,age,name,score,tags,vip
+users,a,0,,30,ana,,,true
+users,a,1,,,"bob, jr",1.5,"[""x"",2]",
+users,b,0,"{""city"":""Lima""}",,cid,,,
+`
+	if out != want {
+		t.Errorf("csv =\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	e := exportEngine(t)
+	dir := t.TempDir()
+	tests := []struct {
+		file  string
+		scope Scope
+	}{
+		{"out.xml", Scope{DB: "shop"}},
+		{"out.json", Scope{DB: "nope"}},
+		{"out.json", Scope{DB: "shop", Collection: "nope"}},
+		{"out.csv", Scope{DB: "shop", Collection: "users", Document: "nope"}},
+	}
+	for _, tc := range tests {
+		path := filepath.Join(dir, tc.file)
+		if _, err := ExportTo(e, tc.scope, path); err == nil {
+			t.Errorf("ExportTo(%s, %+v): want error", tc.file, tc.scope)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("ExportTo(%s, %+v) left a file behind", tc.file, tc.scope)
+		}
+		if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+			t.Errorf("ExportTo(%s, %+v) left a .tmp behind", tc.file, tc.scope)
+		}
+	}
+}
+
+// keyedEngine: shop/people con claves objectid y un documento d con dos objetos.
+func keyedEngine(t *testing.T) *engine.Engine {
+	return newTestEngine(t, "shop", keys{"people": db.KeysObjectID}, docs{
+		"people/d": {{"name": "a"}, {"name": "b"}},
+	})
+}
+
+// keysByName → name -> db.KeyField de los objetos de shop/people/d.
+func keysByName(t *testing.T, e *engine.Engine) map[interface{}]interface{} {
+	t.Helper()
+	objs, err := e.ListObjects("shop", "people", "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(map[interface{}]interface{})
+	for _, obj := range objs {
+		out[obj.Fields["name"]] = obj.Fields[db.KeyField]
+	}
+	return out
+}
+
+// Un export de una colección con claves se vuelve a importar con las mismas
+// claves, en cualquier formato.
+func TestExportImportKeys(t *testing.T) {
+	for _, file := range []string{"people.json", "people.ndjson", "people.csv"} {
+		t.Run(file, func(t *testing.T) {
+			src := keyedEngine(t)
+			path := filepath.Join(t.TempDir(), file)
+			if _, err := ExportTo(src, Scope{DB: "shop", Collection: "people"}, path); err != nil {
+				t.Fatal(err)
+			}
+
+			dst := newTestEngine(t, "shop", keys{"people": db.KeysObjectID}, nil)
+			report, err := ImportFrom(dst, path, Scope{DB: "shop", Collection: "people"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Inserted != 2 || len(report.Errors) != 0 {
+				t.Fatalf("import = %+v, want 2 objects and no errors", report)
+			}
+			if got, want := keysByName(t, dst), keysByName(t, src); !reflect.DeepEqual(got, want) {
+				t.Errorf("imported keys = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestExportCSVHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.csv")
+	if _, err := ExportTo(keyedEngine(t), Scope{DB: "shop"}, path); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	header, _ := bufio.NewReader(f).ReadString('\n')
+	if want := "_collection,_document,_oid," + db.KeyField + ",name"; strings.TrimSpace(header) != want {
+		t.Errorf("header = %q, want %q", strings.TrimSpace(header), want)
+	}
+}
+
+// Los CSV exportados antes de _oid traen el ID entero en _id: se sigue ignorando.
+func TestImportLegacyCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "d.csv")
+	if err := os.WriteFile(path, []byte("_collection,_document,_id,name\npeople,d,7,a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	e := newTestEngine(t, "shop", nil, nil)
+	if _, err := ImportFrom(e, path, Scope{DB: "shop"}); err != nil {
+		t.Fatal(err)
+	}
+	objs, err := e.ListObjects("shop", "people", "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 1 || !reflect.DeepEqual(objs[0].Fields, map[string]interface{}{"name": "a"}) {
+		t.Errorf("imported %v, want a single {name: a}", objs)
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"machDB/src/internal/engine"
+)
+
+// docs describe el contenido de una base de datos de prueba: "colección/documento"
+// -> objetos. Un documento sin objetos se crea vacío.
+type docs map[string][]map[string]interface{}
+
+// keys da el tipo de clave (db.KeysObjectID, db.KeysUUID) de algunas
+// colecciones; las que no estén en docs se crean vacías.
+type keys map[string]string
+
+// newTestEngine crea un engine con la base de datos dbName, las colecciones de
+// colKeys con sus claves y los documentos de spec (y sus colecciones), en orden
+// de nombre.
+func newTestEngine(t testing.TB, dbName string, colKeys keys, spec docs) *engine.Engine {
+	t.Helper()
+	e := engine.NewEngine()
+	if err := e.CreateDatabase(dbName); err != nil {
+		t.Fatal(err)
+	}
+	cols := make(map[string]bool)
+	createCollection := func(col string) {
+		if cols[col] {
+			return
+		}
+		cols[col] = true
+		if err := e.CreateCollection(dbName, col); err != nil {
+			t.Fatal(err)
+		}
+		if kind, ok := colKeys[col]; ok {
+			if err := e.SetCollectionKeys(dbName, col, kind); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for _, col := range sortedNames(colKeys) {
+		createCollection(col)
+	}
+	for _, path := range sortedNames(spec) {
+		col, doc, ok := strings.Cut(path, "/")
+		if !ok {
+			t.Fatalf("bad document path %q, want collection/document", path)
+		}
+		createCollection(col)
+		if err := e.CreateDocument(dbName, col, doc); err != nil {
+			t.Fatal(err)
+		}
+		if len(spec[path]) == 0 {
+			continue
+		}
+		if _, err := e.InsertObjects(dbName, col, doc, spec[path]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return e
+}
@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	db "machDB/src/internal/db"
+	"machDB/src/internal/engine"
+)
+
+// ImportReport resume un import: filas leídas, objetos insertados y los errores
+// de cada fila descartada (el resto del archivo se importa igual).
+type ImportReport struct {
+	Format   string
+	Rows     int
+	Inserted int
+	Errors   []string
+}
+
+// ImportFrom lee path (JSON de export, array JSON de objetos, NDJSON o CSV) y lo
+// inserta en bloque. Las filas que no dicen su colección o documento van a
+// target.Collection y a un documento con el nombre del archivo. La base de datos
+// es target.DB o, si está vacía, la del archivo (solo en el JSON de export).
+func ImportFrom(e *engine.Engine, path string, target Scope) (*ImportReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.Document == "" {
+		target.Document = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	report := &ImportReport{Format: detectFormat(path, data)}
+	var rows []Row
+	switch report.Format {
+	case FormatJSON:
+		var dbName string
+		rows, dbName, err = readJSON(data, report)
+		if err != nil {
+			return nil, err
+		}
+		if target.DB == "" {
+			target.DB = dbName
+		}
+	case FormatNDJSON:
+		rows = readNDJSON(data, report)
+	case FormatCSV:
+		rows, err = readCSV(data, report)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if target.DB == "" {
+		return nil, fmt.Errorf("no target database for import")
+	}
+
+	var batches []engine.BulkDoc
+	pos := make(map[[2]string]int)
+	for n, r := range rows {
+		if r.Collection == "" {
+			r.Collection = target.Collection
+		}
+		if r.Document == "" {
+			r.Document = target.Document
+		}
+		if r.Collection == "" {
+			report.Errors = append(report.Errors, fmt.Sprintf("fila %d: sin colección destino", n+1))
+			continue
+		}
+		key := [2]string{r.Collection, r.Document}
+		i, ok := pos[key]
+		if !ok {
+			i = len(batches)
+			pos[key] = i
+			batches = append(batches, engine.BulkDoc{Collection: r.Collection, Document: r.Document})
+		}
+		batches[i].Objects = append(batches[i].Objects, r.Fields)
+	}
+
+	report.Inserted, err = e.BulkInsert(target.DB, batches)
+	return report, err
+}
+
+// detectFormat usa la extensión para .csv y mira el contenido para el resto:
+// un único valor JSON es JSON, varias líneas con objetos es NDJSON y cualquier
+// otra cosa se lee como CSV.
+func detectFormat(path string, data []byte) string {
+	if f, _ := FormatFromPath(path); f == FormatCSV {
+		return FormatCSV
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return FormatCSV
+	}
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	var v json.RawMessage
+	if err := dec.Decode(&v); err == nil {
+		if _, err := dec.Token(); err == io.EOF {
+			return FormatJSON
+		}
+	}
+	return FormatNDJSON
+}
+
+func decodeValue(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// readJSON acepta el formato de Export o un array de objetos (planos o {id, fields}).
+func readJSON(data []byte, report *ImportReport) ([]Row, string, error) {
+	trimmed := bytes.TrimSpace(data)
+	if trimmed[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, "", err
+		}
+		var rows []Row
+		for n, item := range items {
+			report.Rows++
+			r, err := rowFromJSON(item)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("elemento %d: %v", n+1, err))
+				continue
+			}
+			rows = append(rows, r)
+		}
+		return rows, "", nil
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &probe); err != nil {
+		return nil, "", err
+	}
+	if _, ok := probe["database"]; !ok {
+		report.Rows++
+		r, err := rowFromJSON(trimmed)
+		if err != nil {
+			return nil, "", err
+		}
+		return []Row{r}, "", nil
+	}
+
+	var exp Export
+	if err := decodeValue(trimmed, &exp); err != nil {
+		return nil, "", err
+	}
+	var rows []Row
+	add := func(colName, docName string, objs []*db.Object) {
+		for _, obj := range objs {
+			report.Rows++
+			rows = append(rows, Row{Collection: colName, Document: docName, Fields: obj.Fields})
+		}
+	}
+	switch {
+	case exp.Objects != nil:
+		add(exp.Collection, exp.Document, exp.Objects)
+	case exp.Documents != nil:
+		for _, docName := range sortedNames(exp.Documents) {
+			add(exp.Collection, docName, exp.Documents[docName])
+		}
+	default:
+		for _, colName := range sortedNames(exp.Collections) {
+			col := exp.Collections[colName]
+			for _, docName := range sortedNames(col.Documents) {
+				add(colName, docName, col.Documents[docName])
+			}
+		}
+	}
+	return rows, exp.Database, nil
+}
+
+// rowFromJSON acepta una línea de export ({collection, document, id, fields}),
+// un Object ({id, fields}) o un objeto plano con los campos.
+func rowFromJSON(data []byte) (Row, error) {
+	var m map[string]interface{}
+	if err := decodeValue(data, &m); err != nil {
+		return Row{}, err
+	}
+	if m == nil {
+		return Row{}, fmt.Errorf("not an object")
+	}
+	if fields, ok := m["fields"].(map[string]interface{}); ok {
+		r := Row{Fields: fields}
+		r.Collection, _ = m["collection"].(string)
+		r.Document, _ = m["document"].(string)
+		normalizeFields(r.Fields)
+		return r, nil
+	}
+	normalizeFields(m)
+	return Row{Fields: m}, nil
+}
+
+func readNDJSON(data []byte, report *ImportReport) []Row {
+	var rows []Row
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		report.Rows++
+		r, err := rowFromJSON(line)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("línea %d: %v", n, err))
+			continue
+		}
+		rows = append(rows, r)
+	}
+	if err := sc.Err(); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	return rows
+}
+
+// readCSV usa la primera fila como cabecera. _collection y _document (las del
+// export) ubican la fila, _oid se ignora (también _id en los exports anteriores,
+// que no tienen _oid) y las celdas vacías no crean campo. Las claves de
+// db.KeyField se conservan siempre como string.
+func readCSV(data []byte, report *ImportReport) ([]Row, error) {
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	skip := "_oid"
+	if !slices.Contains(header, skip) {
+		skip = "_id"
+	}
+
+	var rows []Row
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		report.Rows++
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		if len(record) != len(header) {
+			report.Errors = append(report.Errors, fmt.Sprintf("línea %d: %d columnas, se esperaban %d", line, len(record), len(header)))
+			continue
+		}
+		r := Row{Fields: make(map[string]interface{})}
+		for n, name := range header {
+			switch name {
+			case "_collection":
+				r.Collection = record[n]
+			case "_document":
+				r.Document = record[n]
+			case skip:
+			case db.KeyField:
+				if record[n] != "" {
+					r.Fields[name] = record[n]
+				}
+			default:
+				if record[n] != "" {
+					r.Fields[name] = csvValue(record[n])
+				}
+			}
+		}
+		rows = append(rows, r)
+	}
+	return rows, nil
+}
+
+// csvValue es la inversa de csvCell: números, true/false, JSON anidado o string.
+func csvValue(cell string) interface{} {
+	switch cell {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if i, err := strconv.Atoi(cell); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(cell, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return f
+	}
+	if cell[0] == '{' || cell[0] == '[' {
+		var v interface{}
+		if err := decodeValue([]byte(cell), &v); err == nil {
+			return db.NormalizeNumber(v)
+		}
+	}
+	return cell
+}
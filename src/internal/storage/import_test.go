@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"machDB/src/internal/engine"
+)
+
+// contents resume dbName como colección/documento -> campos de cada objeto.
+func contents(t *testing.T, e *engine.Engine, dbName string) map[string][]map[string]interface{} {
+	t.Helper()
+	out := make(map[string][]map[string]interface{})
+	cols, err := e.ListCollections(dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, col := range cols {
+		docs, _ := e.ListDocuments(dbName, col)
+		for _, doc := range docs {
+			objs, _ := e.ListObjects(dbName, col, doc)
+			for _, obj := range objs {
+				out[col+"/"+doc] = append(out[col+"/"+doc], obj.Fields)
+			}
+		}
+	}
+	return out
+}
+
+// Lo exportado de una base de datos se importa en otra igual, con los mismos
+// tipos de valores, en cualquier formato.
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, file := range []string{"shop.json", "shop.ndjson", "shop.csv"} {
+		t.Run(file, func(t *testing.T) {
+			src := exportEngine(t)
+			path := filepath.Join(t.TempDir(), file)
+			if _, err := ExportTo(src, Scope{DB: "shop"}, path); err != nil {
+				t.Fatal(err)
+			}
+
+			dst := engine.NewEngine()
+			if err := dst.CreateDatabase("copy"); err != nil {
+				t.Fatal(err)
+			}
+			report, err := ImportFrom(dst, path, Scope{DB: "copy"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Rows != 4 || report.Inserted != 4 || len(report.Errors) != 0 {
+				t.Errorf("report = %+v, want 4 rows inserted", report)
+			}
+			if got, want := contents(t, dst, "copy"), contents(t, src, "shop"); !reflect.DeepEqual(got, want) {
+				t.Errorf("imported\n%v\nwant\n%v", got, want)
+			}
+			// los índices se construyen al importar
+			if objs, err := dst.Find("name", "cid", "copy"); err != nil || len(objs) != 1 {
+				t.Errorf("Find(name, cid) after import = %v, %v", objs, err)
+			}
+		})
+	}
+}
+
+func TestImportFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		format  string
+		target  Scope
+		want    map[string][]map[string]interface{}
+		errors  int
+		wantErr bool
+	}{
+		{
+			name:   "array of plain objects",
+			file:   "people.json",
+			data:   `[{"name":"ana","age":30},{"id":7,"fields":{"name":"bob"}}]`,
+			format: FormatJSON,
+			target: Scope{DB: "shop", Collection: "users"},
+			want:   map[string][]map[string]interface{}{"users/people": {{"name": "ana", "age": 30}, {"name": "bob"}}},
+		},
+		{
+			name:   "single object",
+			file:   "one.data",
+			data:   `{"name":"ana"}`,
+			format: FormatJSON,
+			target: Scope{DB: "shop", Collection: "users", Document: "d"},
+			want:   map[string][]map[string]interface{}{"users/d": {{"name": "ana"}}},
+		},
+		{
+			name:   "ndjson with a bad line",
+			file:   "people.txt",
+			data:   "{\"name\":\"ana\"}\nnot json\n\n{\"collection\":\"orders\",\"document\":\"o\",\"fields\":{\"total\":2.5}}\n",
+			format: FormatNDJSON,
+			target: Scope{DB: "shop", Collection: "users"},
+			want: map[string][]map[string]interface{}{
+				"users/people": {{"name": "ana"}},
+				"orders/o":     {{"total": 2.5}},
+			},
+			errors: 1,
+		},
+		{
+			name:   "csv values",
+			file:   "people.csv",
+			data:   "name,age,vip,score,tags,note\nana,30,true,1.5,\"[1,2]\",\nbob,x,false,,,hi\nshort,1\n",
+			format: FormatCSV,
+			target: Scope{DB: "shop", Collection: "users"},
+			want: map[string][]map[string]interface{}{"users/people": {
+				{"name": "ana", "age": 30, "vip": true, "score": 1.5, "tags": []interface{}{1, 2}},
+				{"name": "bob", "age": "x", "vip": false, "note": "hi"},
+			}},
+			errors: 1,
+		},
+		{
+			name:   "no target collection",
+			file:   "people.ndjson",
+			data:   "{\"name\":\"ana\"}\n{\"name\":\"bob\"}\n",
+			format: FormatNDJSON,
+			target: Scope{DB: "shop"},
+			want:   map[string][]map[string]interface{}{},
+			errors: 2,
+		},
+		{
+			name:    "no target database",
+			file:    "people.json",
+			data:    `[{"name":"ana"}]`,
+			target:  Scope{Collection: "users"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, []byte(tc.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			e := engine.NewEngine()
+			if err := e.CreateDatabase("shop"); err != nil {
+				t.Fatal(err)
+			}
+			report, err := ImportFrom(e, path, tc.target)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Format != tc.format {
+				t.Errorf("format = %s, want %s", report.Format, tc.format)
+			}
+			if len(report.Errors) != tc.errors {
+				t.Errorf("errors = %q, want %d", report.Errors, tc.errors)
+			}
+			if got := contents(t, e, "shop"); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("imported\n%v\nwant\n%v", got, tc.want)
+			}
+		})
+	}
+}
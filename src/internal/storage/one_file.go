@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"encoding/json"
+
+	db "machDB/src/internal/db"
+)
+
+// fileLayout guarda todo en un único archivo con db.OneFileDB; el WAL va al lado.
+type fileLayout struct {
+	file *db.OneFileDB
+}
+
+func (l *fileLayout) walPath() string {
+	return l.file.Path + ".wal"
+}
+
+func (l *fileLayout) read() (map[string]*db.Database, error) {
+	databases, _, err := l.file.Load()
+	return databases, err
+}
+
+func (l *fileLayout) write(databases map[string]*db.Database, meta json.RawMessage) error {
+	return l.file.Save(databases, meta)
+}
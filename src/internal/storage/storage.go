@@ -1,37 +1,131 @@
 package storage
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
-	e "machDB/src/internal/engine"
+
+	db "machDB/src/internal/db"
+	"machDB/src/internal/engine"
 )
 
-type Storage struct{
-	path_save string
-	path_read string 
-	mu sync.RWMutex  
-} 
+// walFile es el nombre del write-ahead log dentro de basePath.
+const walFile = "wal.log"
+
+// collectionMetaFile guarda, dentro del directorio de una colección, su
+// db.CollectionMeta. No acaba en .json para que no se lea como un documento.
+const collectionMetaFile = "collection.meta"
+
+// OneFileExt es la extensión que activa el formato de archivo único.
+const OneFileExt = ".machdb"
+
+// layout es la forma de guardar el snapshot en disco.
+type layout interface {
+	read() (map[string]*db.Database, error)
+	write(databases map[string]*db.Database, meta json.RawMessage) error
+	walPath() string
+}
 
-func NewStorage(path string)*Storage{
-	path_read := "/db" 
+// Storage es dueño del layout en disco y del WAL. Hay dos layouts:
+//
+//	directorio:     <base>/<db>/<collection>/<doc>.json (más collection.meta si
+//	                la colección tiene claves) y <base>/wal.log
+//	archivo único:  <base> (ver db.OneFileDB) y <base>.wal
+//
+// El engine no sabe nada de archivos; Storage lee y escribe su estado y se
+// engancha como su Journal.
+type Storage struct {
+	basePath string
+	layout   layout
+	wal      *WAL
+	mu       sync.Mutex
+}
+
+// NewStorage elige el layout según basePath: archivo único si ya es un archivo
+// machDB o termina en .machdb, directorio en otro caso.
+func NewStorage(basePath string) *Storage {
+	var l layout
+	if filepath.Ext(basePath) == OneFileExt || db.IsOneFileDB(basePath) {
+		l = &fileLayout{file: db.NewOneFileDB(basePath)}
+	} else {
+		l = &dirLayout{basePath: basePath, known: make(map[string]bool)}
+	}
 	return &Storage{
-		path_save string, 
-		path_read string 
+		basePath: basePath,
+		layout:   l,
 	}
 }
 
-func (e *) LoadFromDisk() error {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+// BasePath devuelve el directorio raíz de los datos.
+func (s *Storage) BasePath() string {
+	return s.basePath
+}
 
-	// Limpiar estructura actual
-	e.Databases = make(map[string]*db.Database)
-	e.Index = make(InvertedIndex)
+// LoadFromDisk lee el snapshot JSON de basePath, reemplaza el estado del engine,
+// reproduce encima el WAL (saltando lo que el snapshot ya tiene, ver
+// engine.Replay) y deja el WAL enganchado como journal del engine para que cada
+// mutación posterior quede registrada antes de aplicarse.
+func (s *Storage) LoadFromDisk(e *engine.Engine) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Leer directorio base (idx.basePath), por ejemplo "/db"
-	dbEntries, err := os.ReadDir(idx.basePath)
+	e.SetJournal(nil)
+	if s.wal != nil {
+		s.wal.Close()
+		s.wal = nil
+	}
+
+	databases, err := s.layout.read()
+	if err != nil {
+		return err
+	}
+	snapshotLSN := maxLSN(databases)
+	e.Replace(databases)
+
+	walPath := s.layout.walPath()
+	if err := os.MkdirAll(filepath.Dir(walPath), 0o755); err != nil {
+		return err
+	}
+	wal, err := OpenWAL(walPath)
 	if err != nil {
 		return err
 	}
+	wal.advance(snapshotLSN)
+	if err := wal.Replay(e.Replay); err != nil {
+		wal.Close()
+		return err
+	}
+	s.wal = wal
+	e.SetJournal(wal)
+	return nil
+}
+
+// dirLayout: <base>/<db>/<collection>/<doc>.json
+type dirLayout struct {
+	basePath string
+	known    map[string]bool // DBs leídas o escritas por nosotros; solo esas se borran
+}
+
+func (l *dirLayout) walPath() string {
+	return filepath.Join(l.basePath, walFile)
+}
+
+// read lee <base>/<db>/<collection>/<doc>.json. Si basePath no existe devuelve
+// un mapa vacío.
+func (l *dirLayout) read() (map[string]*db.Database, error) {
+	databases := make(map[string]*db.Database)
+
+	dbEntries, err := os.ReadDir(l.basePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return databases, nil
+		}
+		return nil, err
+	}
 
 	for _, dbEntry := range dbEntries {
 		if !dbEntry.IsDir() {
@@ -39,11 +133,11 @@ func (e *) LoadFromDisk() error {
 		}
 		dbName := dbEntry.Name()
 		database := db.NewDatabase(dbName)
-		dbPath := filepath.Join(idx.basePath, dbName)
+		dbPath := filepath.Join(l.basePath, dbName)
 
 		colEntries, err := os.ReadDir(dbPath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		for _, colEntry := range colEntries {
@@ -52,17 +146,20 @@ func (e *) LoadFromDisk() error {
 			}
 			colName := colEntry.Name()
 			if err := database.CreateCollection(colName); err != nil {
-				return err
+				return nil, err
 			}
 			collection, err := database.GetCollection(colName)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			colPath := filepath.Join(dbPath, colName)
+			if err := readCollectionMeta(filepath.Join(colPath, collectionMetaFile), collection); err != nil {
+				return nil, fmt.Errorf("%s/%s: %w", dbName, colName, err)
+			}
 			docEntries, err := os.ReadDir(colPath)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			for _, docEntry := range docEntries {
@@ -70,51 +167,125 @@ func (e *) LoadFromDisk() error {
 					continue
 				}
 				docName := strings.TrimSuffix(docEntry.Name(), ".json")
-				docPath := filepath.Join(colPath, docEntry.Name())
-
-				f, err := os.Open(docPath)
+				doc, err := readDocument(filepath.Join(colPath, docEntry.Name()))
 				if err != nil {
-					return err
-				}
-
-				var doc db.Document
-				dec := json.NewDecoder(f)
-				if err := dec.Decode(&doc); err != nil {
-					f.Close()
-					return err
-				}
-				f.Close()
-
-				collection.Documents[docName] = &doc
-
-				// Reconstruir índice invertido para cada objeto del documento
-				for oid, obj := range doc.Objects {
-					for k, v := range obj.Fields {
-						valStr := fmt.Sprintf("%v", v)
-						if _, ok := idx.Index[k]; !ok {
-							idx.Index[k] = make(map[string][]ObjectRef)
-						}
-						ref := ObjectRef{DB: dbName, Collection: colName, Document: docName, ID: oid}
-						idx.Index[k][valStr] = append(idx.Index[k][valStr], ref)
-					}
+					return nil, fmt.Errorf("%s/%s/%s: %w", dbName, colName, docName, err)
 				}
+				doc.Name = docName
+				collection.Documents[docName] = doc
 			}
 		}
 
-		idx.Databases[dbName] = database
+		databases[dbName] = database
+		l.known[dbName] = true
 	}
 
+	return databases, nil
+}
+
+// readCollectionMeta aplica a col el archivo de metadatos de la colección, si
+// existe.
+func readCollectionMeta(path string, col *db.Collection) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var meta db.CollectionMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return err
+	}
+	col.Keys = meta.Keys
 	return nil
 }
-func (idx *Index) FlushToDisk() error {
-	idx.mu.RLock()
-	defer idx.mu.RUnlock()
 
-	for dbName, database := range idx.Databases {
-		dbPath := filepath.Join(idx.basePath, dbName)
+func readDocument(path string) (*db.Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var doc db.Document
+	if err := json.NewDecoder(f).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Objects == nil {
+		doc.Objects = []*db.Object{}
+	}
+	return &doc, nil
+}
+
+// FlushToDisk escribe el estado del engine con el layout de Storage. Escribe un
+// Engine.Snapshot, así que el engine sigue atendiendo mientras tanto. Al
+// terminar quita del WAL lo que ya está en el snapshot y deja lo posterior.
+//
+// Cada documento se guarda con el LSN del WAL en el instante del snapshot, así
+// que si el proceso muere antes de compactar el WAL (o a mitad del snapshot) el
+// siguiente LoadFromDisk no repite lo que ya se escribió.
+func (s *Storage) FlushToDisk(e *engine.Engine) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	meta, err := json.Marshal(map[string]interface{}{"indexed_fields": e.IndexedFields()})
+	if err != nil {
+		return err
+	}
+
+	var lsn uint64
+	databases := e.Snapshot(func() {
+		if s.wal != nil {
+			lsn = s.wal.LSN()
+		}
+	})
+	for _, database := range databases {
+		for _, col := range database.Collections {
+			for _, doc := range col.Documents {
+				doc.LSN = lsn
+			}
+		}
+	}
+	if err := s.layout.write(databases, meta); err != nil {
+		return err
+	}
+	if s.wal != nil {
+		return s.wal.Compact(lsn)
+	}
+	return nil
+}
+
+// maxLSN → el mayor db.Document.LSN de databases
+func maxLSN(databases map[string]*db.Database) uint64 {
+	var lsn uint64
+	for _, database := range databases {
+		for _, col := range database.Collections {
+			for _, doc := range col.Documents {
+				if doc.LSN > lsn {
+					lsn = doc.LSN
+				}
+			}
+		}
+	}
+	return lsn
+}
+
+// write escribe cada documento en un .tmp y lo renombra, y borra lo que ya no
+// existe en memoria. Los metadatos de índices no se guardan: se reconstruyen.
+// Antes de volver hace fsync de los directorios, así que el snapshot está en
+// disco cuando el llamador vacía el WAL.
+func (l *dirLayout) write(databases map[string]*db.Database, _ json.RawMessage) error {
+	if err := os.MkdirAll(l.basePath, 0o755); err != nil {
+		return err
+	}
+
+	for dbName, database := range databases {
+		dbPath := filepath.Join(l.basePath, dbName)
 		if err := os.MkdirAll(dbPath, 0o755); err != nil {
 			return err
 		}
+		l.known[dbName] = true
 
 		for colName, col := range database.Collections {
 			colPath := filepath.Join(dbPath, colName)
@@ -122,35 +293,126 @@ func (idx *Index) FlushToDisk() error {
 				return err
 			}
 
+			if err := writeCollectionMeta(filepath.Join(colPath, collectionMetaFile), col); err != nil {
+				return err
+			}
 			for docName, doc := range col.Documents {
-				// Serializar documento como JSON
 				outPath := filepath.Join(colPath, docName+".json")
-				tmpPath := outPath + ".tmp"
-
-				f, err := os.Create(tmpPath)
-				if err != nil {
-					return err
-				}
-				enc := json.NewEncoder(f)
-				enc.SetIndent("", "  ")
-				if err := enc.Encode(doc); err != nil {
-					f.Close()
-					return err
-				}
-				f.Close()
-				// renombrar atómico
-				if err := os.Rename(tmpPath, outPath); err != nil {
+				if err := writeDocument(outPath, doc); err != nil {
 					return err
 				}
 			}
+			if err := removeStale(colPath, func(name string, isDir bool) bool {
+				_, ok := col.Documents[strings.TrimSuffix(name, ".json")]
+				return isDir || !strings.HasSuffix(name, ".json") || ok
+			}); err != nil {
+				return err
+			}
+			if err := db.SyncDir(colPath); err != nil {
+				return err
+			}
+		}
+		if err := removeStale(dbPath, func(name string, isDir bool) bool {
+			_, ok := database.Collections[name]
+			return !isDir || ok
+		}); err != nil {
+			return err
+		}
+		if err := db.SyncDir(dbPath); err != nil {
+			return err
 		}
 	}
-	return nil
+	for dbName := range l.known {
+		if _, ok := databases[dbName]; ok {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(l.basePath, dbName)); err != nil {
+			return err
+		}
+		delete(l.known, dbName)
+	}
+	return db.SyncDir(l.basePath)
 }
+
+// writeCollectionMeta guarda los metadatos de col en path, o lo borra si no hay
+// nada que guardar.
+func writeCollectionMeta(path string, col *db.Collection) error {
+	if col.Keys == db.KeysNone {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	raw, err := json.Marshal(db.CollectionMeta{Keys: col.Keys})
+	if err != nil {
+		return err
+	}
+	return writeFile(path, func(w io.Writer) error {
+		_, err := w.Write(raw)
+		return err
+	})
+}
+
+func writeDocument(outPath string, doc *db.Document) error {
+	// Serializar documento como JSON
+	return writeFile(outPath, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	})
+}
+
+// writeFile escribe path con fill en un .tmp, hace fsync y lo renombra: path
+// tiene el contenido anterior o el nuevo completo. El fsync del directorio lo
+// hace quien llama, una vez por directorio.
+func writeFile(path string, fill func(w io.Writer) error) error {
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	err = fill(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	// renombrar atómico
+	return os.Rename(tmpPath, path)
+}
+
+// removeStale borra las entradas de dir para las que keep devuelve false.
+func removeStale(dir string, keep func(name string, isDir bool) bool) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if keep(entry.Name(), entry.IsDir()) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
 			return err
-				}
-			}
 		}
 	}
 	return nil
 }
+
+// Close cierra el WAL. El engine deja de registrar mutaciones.
+func (s *Storage) Close(e *engine.Engine) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.SetJournal(nil)
+	if s.wal == nil {
+		return nil
+	}
+	err := s.wal.Close()
+	s.wal = nil
+	return err
+}
@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"machDB/src/internal/engine"
+)
+
+// openStorage carga path en un engine nuevo con el WAL enganchado.
+func openStorage(t *testing.T, path string) (*Storage, *engine.Engine) {
+	t.Helper()
+	s := NewStorage(path)
+	e := engine.NewEngine()
+	if err := s.LoadFromDisk(e); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close(e) })
+	return s, e
+}
+
+// dump resume el engine como db/colección/documento -> "id:name" de cada objeto.
+func dump(t *testing.T, e *engine.Engine) map[string][]string {
+	t.Helper()
+	out := make(map[string][]string)
+	for _, dbName := range e.ListDatabases() {
+		out[dbName] = nil
+		cols, _ := e.ListCollections(dbName)
+		for _, col := range cols {
+			docs, _ := e.ListDocuments(dbName, col)
+			for _, doc := range docs {
+				objs, _ := e.ListObjects(dbName, col, doc)
+				vals := []string{}
+				for _, obj := range objs {
+					vals = append(vals, fmt.Sprintf("%d:%v", obj.ID, obj.Fields["name"]))
+				}
+				sort.Strings(vals)
+				out[dbName+"/"+col+"/"+doc] = vals
+			}
+		}
+	}
+	return out
+}
+
+// steps ejecuta cada paso sobre e y falla el test en el primer error.
+func steps(t *testing.T, e *engine.Engine, fns ...func(e *engine.Engine) error) {
+	t.Helper()
+	for _, fn := range fns {
+		if err := fn(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func insert(col, doc string, names ...string) func(e *engine.Engine) error {
+	return func(e *engine.Engine) error {
+		objs := make([]map[string]interface{}, len(names))
+		for n, name := range names {
+			objs[n] = map[string]interface{}{"name": name}
+		}
+		_, err := e.InsertObjects("shop", col, doc, objs)
+		return err
+	}
+}
+
+func createShop(e *engine.Engine) error {
+	if err := e.CreateDatabase("shop"); err != nil {
+		return err
+	}
+	if err := e.CreateCollection("shop", "users"); err != nil {
+		return err
+	}
+	if err := e.CreateDocument("shop", "users", "a"); err != nil {
+		return err
+	}
+	return e.CreateDocument("shop", "users", "b")
+}
+
+// Un crash después de escribir el snapshot deja en disco el snapshot nuevo y el
+// WAL (o parte de los archivos) de antes: recargar no repite nada.
+func TestWALReplayAfterCrash(t *testing.T) {
+	tests := []struct {
+		name string
+		file string // vacío: layout de directorio
+		// before se guarda con un flush completo; after queda solo en el WAL
+		// cuando empieza el flush que se interrumpe
+		before, after []func(e *engine.Engine) error
+		// stale son archivos (relativos a la base) que vuelven a su contenido
+		// de antes del flush interrumpido
+		stale []string
+	}{
+		{
+			name:  "crash before compacting the wal",
+			after: []func(e *engine.Engine) error{createShop, insert("users", "a", "x", "y")},
+		},
+		{
+			name:  "one file database",
+			file:  "shop.machdb",
+			after: []func(e *engine.Engine) error{createShop, insert("users", "a", "x", "y")},
+		},
+		{
+			name:   "crash halfway through the snapshot",
+			before: []func(e *engine.Engine) error{createShop, insert("users", "a", "x")},
+			after:  []func(e *engine.Engine) error{insert("users", "a", "y"), insert("users", "b", "z")},
+			stale:  []string{"shop/users/b.json"},
+		},
+		{
+			name:   "document deleted and created again",
+			before: []func(e *engine.Engine) error{createShop, insert("users", "a", "x")},
+			after: []func(e *engine.Engine) error{
+				func(e *engine.Engine) error { return e.DeleteDocument("shop", "users", "a") },
+				func(e *engine.Engine) error { return e.CreateDocument("shop", "users", "a") },
+				insert("users", "a", "y"),
+			},
+		},
+		{
+			name:   "database deleted and created again",
+			before: []func(e *engine.Engine) error{createShop, insert("users", "a", "x")},
+			after: []func(e *engine.Engine) error{
+				func(e *engine.Engine) error { return e.DeleteDatabase("shop") },
+				createShop,
+				insert("users", "b", "y"),
+			},
+			stale: []string{"shop/users/a.json"},
+		},
+		{
+			name:   "transaction",
+			before: []func(e *engine.Engine) error{createShop},
+			after: []func(e *engine.Engine) error{
+				func(e *engine.Engine) error {
+					tx := e.Begin()
+					if _, err := tx.InsertObjects("shop", "users", "a", []map[string]interface{}{{"name": "x"}}); err != nil {
+						return err
+					}
+					if err := tx.CreateDocument("shop", "users", "c"); err != nil {
+						return err
+					}
+					if _, err := tx.InsertObjects("shop", "users", "c", []map[string]interface{}{{"name": "y"}}); err != nil {
+						return err
+					}
+					return tx.Commit()
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			base := dir
+			if tc.file != "" {
+				base = filepath.Join(dir, tc.file)
+			}
+			s, e := openStorage(t, base)
+			steps(t, e, tc.before...)
+			if err := s.FlushToDisk(e); err != nil {
+				t.Fatal(err)
+			}
+			steps(t, e, tc.after...)
+			want := dump(t, e)
+
+			// lo que había en disco cuando empezó el flush interrumpido
+			saved := make(map[string][]byte)
+			for _, name := range append([]string{filepath.Base(s.layout.walPath())}, tc.stale...) {
+				path := filepath.Join(filepath.Dir(s.layout.walPath()), name)
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				saved[path] = data
+			}
+			if err := s.FlushToDisk(e); err != nil {
+				t.Fatal(err)
+			}
+			s.Close(e)
+			for path, data := range saved {
+				if err := os.WriteFile(path, data, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, reloaded := openStorage(t, base)
+			if got := dump(t, reloaded); !reflect.DeepEqual(got, want) {
+				t.Errorf("state after reload =\n%v\nwant\n%v", got, want)
+			}
+		})
+	}
+}
+
+// Sin flush, lo registrado en el WAL se recupera al recargar; una última línea
+// cortada se descarta y el siguiente ID sigue sin repetirse.
+func TestWALReplayWithoutFlush(t *testing.T) {
+	dir := t.TempDir()
+	s, e := openStorage(t, dir)
+	steps(t, e, createShop, insert("users", "a", "x", "y"))
+	want := dump(t, e)
+	s.Close(e)
+
+	f, err := os.OpenFile(filepath.Join(dir, walFile), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"insert","db":"shop","coll`)
+	f.Close()
+
+	s, e = openStorage(t, dir)
+	if got := dump(t, e); !reflect.DeepEqual(got, want) {
+		t.Errorf("state after reload = %v, want %v", got, want)
+	}
+	steps(t, e, insert("users", "a", "z"))
+	s.Close(e)
+	_, e = openStorage(t, dir)
+	if got := dump(t, e)["shop/users/a"]; !reflect.DeepEqual(got, []string{"0:x", "1:y", "2:z"}) {
+		t.Errorf("shop/users/a after a second reload = %v", got)
+	}
+}
+
+// FlushToDisk no para a los escritores: lo que se registra mientras escribe el
+// snapshot se queda en el WAL y se recupera al recargar.
+func TestFlushWhileWriting(t *testing.T) {
+	dir := t.TempDir()
+	s, e := openStorage(t, dir)
+	steps(t, e, createShop)
+
+	done := make(chan error)
+	go func() {
+		for n := 0; n < 200; n++ {
+			if err := insert("users", "a", fmt.Sprint(n))(e); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	for flushing := true; flushing; {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			flushing = false
+		default:
+			if err := s.FlushToDisk(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	want := dump(t, e)
+	s.Close(e)
+
+	_, reloaded := openStorage(t, dir)
+	if got := dump(t, reloaded); !reflect.DeepEqual(got, want) {
+		t.Errorf("state after reload has %d objects in a, want %d", len(got["shop/users/a"]), len(want["shop/users/a"]))
+	}
+}
+
+// Los documentos guardados antes de next_id/lsn traen NextID y LSN: se siguen
+// respetando al cargar (el LSN decide qué del WAL ya está en el snapshot).
+func TestLoadLegacyDocumentKeys(t *testing.T) {
+	dir := t.TempDir()
+	s, e := openStorage(t, dir)
+	steps(t, e, createShop, insert("users", "a", "x", "y"))
+	if err := s.FlushToDisk(e); err != nil {
+		t.Fatal(err)
+	}
+	steps(t, e, insert("users", "a", "z"))
+	s.Close(e)
+
+	// a.json con las claves viejas, diciendo que ya incluye el insert de z
+	path := filepath.Join(dir, "shop", "users", "a.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc["next_id"]; !ok {
+		t.Fatalf("a.json has no next_id: %s", data)
+	}
+	doc["NextID"], doc["LSN"] = 7, doc["lsn"].(float64)+1
+	delete(doc, "next_id")
+	delete(doc, "lsn")
+	if data, err = json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, e = openStorage(t, dir)
+	steps(t, e, insert("users", "a", "w"))
+	if got, want := dump(t, e)["shop/users/a"], []string{"0:x", "1:y", "7:w"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("shop/users/a = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	db "machDB/src/internal/db"
+	"machDB/src/internal/engine"
+)
+
+// WAL es un log append-only de mutaciones, una por línea en JSON. Cada Record
+// hace fsync antes de volver, así que lo que el engine aplica ya está en disco.
+//
+// Record numera las mutaciones con un LSN creciente que sigue después de
+// reabrir: Replay lo sube al mayor del log y advance al de los snapshots.
+type WAL struct {
+	path string
+	f    *os.File
+	lsn  uint64
+	mu   sync.Mutex
+}
+
+// OpenWAL abre (o crea) el log en path para añadir al final.
+func OpenWAL(path string) (*WAL, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &WAL{path: path, f: f}, nil
+}
+
+// Record implementa engine.Journal.
+func (w *WAL) Record(m engine.Mutation) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	m.LSN = w.lsn + 1
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := w.f.Write(line); err != nil {
+		return err
+	}
+	w.lsn = m.LSN
+	return w.f.Sync()
+}
+
+// LSN devuelve el de la última mutación registrada.
+func (w *WAL) LSN() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lsn
+}
+
+// advance sube el LSN a lsn si va por detrás (el log se vació después de un
+// snapshot con ese LSN).
+func (w *WAL) advance(lsn uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if lsn > w.lsn {
+		w.lsn = lsn
+	}
+}
+
+// Replay llama a fn con cada mutación del log en orden. Una última línea
+// incompleta (crash a mitad de escritura) se ignora y se recorta.
+func (w *WAL) Replay(fn func(engine.Mutation) error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(w.f)
+	var offset int64
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(bytes.TrimSpace(line)) > 0 {
+				// escritura cortada: descartarla para no dejar basura antes de la siguiente
+				return w.f.Truncate(offset)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(line))
+
+		m, err := decodeMutation(line)
+		if err != nil {
+			return fmt.Errorf("wal %s line %d: %w", w.path, n, err)
+		}
+		if m.LSN > w.lsn {
+			w.lsn = m.LSN
+		}
+		if err := fn(m); err != nil {
+			return fmt.Errorf("wal %s line %d: %w", w.path, n, err)
+		}
+	}
+}
+
+// decodeMutation usa UseNumber para que los ints del log no vuelvan como float64.
+func decodeMutation(line []byte) (engine.Mutation, error) {
+	var m engine.Mutation
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return m, err
+	}
+	normalizeMutation(&m)
+	return m, nil
+}
+
+// normalizeMutation incluye las mutaciones de una transacción (MutTx).
+func normalizeMutation(m *engine.Mutation) {
+	for _, obj := range m.Objects {
+		normalizeFields(obj)
+	}
+	normalizeFields(m.Filter)
+	normalizeFields(m.Fields)
+	for n := range m.Mutations {
+		normalizeMutation(&m.Mutations[n])
+	}
+}
+
+func normalizeFields(fields map[string]interface{}) {
+	for k, v := range fields {
+		fields[k] = db.NormalizeNumber(v)
+	}
+}
+
+// Compact quita del log las mutaciones con LSN hasta lsn, que ya están en un
+// snapshot, y conserva las posteriores (registradas mientras se escribía). El
+// log nuevo se escribe aparte y se renombra, así que un crash deja el anterior
+// o el nuevo completo.
+func (w *WAL) Compact(lsn uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.lsn <= lsn {
+		if err := w.f.Truncate(0); err != nil {
+			return err
+		}
+		return w.f.Sync()
+	}
+
+	if _, err := w.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	var keep bytes.Buffer
+	r := bufio.NewReader(w.f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var entry struct {
+			LSN uint64 `json:"lsn"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("wal %s: %w", w.path, err)
+		}
+		if entry.LSN > lsn {
+			keep.Write(line)
+		}
+	}
+
+	// el .tmp queda abierto para añadir: tras el rename es el log
+	tmpPath := w.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(keep.Bytes())
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, w.path)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	w.f.Close()
+	w.f = tmp
+	return db.SyncDir(filepath.Dir(w.path))
+}
+
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.f.Close()
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"machDB/src/internal/engine"
+)
+
+// replayAll devuelve las mutaciones del log en orden.
+func replayAll(t *testing.T, w *WAL) []engine.Mutation {
+	t.Helper()
+	var muts []engine.Mutation
+	if err := w.Replay(func(m engine.Mutation) error {
+		muts = append(muts, m)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return muts
+}
+
+func TestWALRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), walFile)
+	w, err := OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := []engine.Mutation{
+		{Op: engine.MutCreateDatabase, DB: "shop"},
+		{Op: engine.MutInsert, DB: "shop", Collection: "users", Document: "a",
+			Objects: []map[string]interface{}{{"age": 30, "score": 1.5, "tags": []interface{}{"x"}}}},
+		{Op: engine.MutTx, DB: "shop", Mutations: []engine.Mutation{
+			{Op: engine.MutModify, DB: "shop", Collection: "users", Document: "a",
+				Filter: map[string]interface{}{"id": 0}, Fields: map[string]interface{}{"age": 31}},
+		}},
+	}
+	for _, m := range recorded {
+		if err := w.Record(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	w, err = OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	got := replayAll(t, w)
+	for n := range recorded {
+		recorded[n].LSN = uint64(n + 1)
+	}
+	if !reflect.DeepEqual(got, recorded) {
+		t.Errorf("replayed\n%+v\nwant\n%+v", got, recorded)
+	}
+
+	// la numeración sigue después de reabrir
+	if err := w.Record(engine.Mutation{Op: engine.MutDeleteDatabase, DB: "shop"}); err != nil {
+		t.Fatal(err)
+	}
+	if lsn := w.LSN(); lsn != 4 {
+		t.Errorf("LSN after reopening = %d, want 4", lsn)
+	}
+}
+
+func TestWALTornLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), walFile)
+	whole := `{"op":"create_db","db":"shop","lsn":1}` + "\n"
+	if err := os.WriteFile(path, []byte(whole+`{"op":"create_db","db":"x`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if got := replayAll(t, w); len(got) != 1 || got[0].DB != "shop" {
+		t.Errorf("replayed %+v, want only create_db shop", got)
+	}
+	if err := w.Record(engine.Mutation{Op: engine.MutCreateDatabase, DB: "next"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := replayAll(t, w); len(got) != 2 || got[1].DB != "next" || got[1].LSN != 2 {
+		t.Errorf("after a new record the log has %+v", got)
+	}
+}
+
+func TestWALCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), walFile)
+	w, err := OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for _, name := range []string{"a", "b", "c"} {
+		if err := w.Record(engine.Mutation{Op: engine.MutCreateDatabase, DB: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Compact(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Record(engine.Mutation{Op: engine.MutCreateDatabase, DB: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	var dbs []string
+	for _, m := range replayAll(t, w) {
+		dbs = append(dbs, m.DB)
+	}
+	if want := []string{"c", "d"}; !reflect.DeepEqual(dbs, want) {
+		t.Errorf("log after Compact(2) = %v, want %v", dbs, want)
+	}
+
+	if err := w.Compact(w.LSN()); err != nil {
+		t.Fatal(err)
+	}
+	if got := replayAll(t, w); len(got) != 0 {
+		t.Errorf("log after compacting everything = %+v", got)
+	}
+}
@@ -0,0 +1,97 @@
+// Tipos del cliente JavaScript de machDB (npm_pkg.js).
+
+/** Valor que se puede guardar en un campo (lo que admite JSON). */
+export type FieldValue = string | number | boolean | null | FieldValue[] | { [key: string]: FieldValue };
+
+/** Campos de un objeto. */
+export type Fields = { [field: string]: FieldValue };
+
+/** Un objeto guardado en un documento, tal como lo devuelve el servidor. */
+export interface MachObject {
+  id: number;
+  fields: Fields;
+}
+
+/** Resultado de un find: los objetos que cumplen la consulta ([] si ninguno). */
+export type QueryResult = MachObject[];
+
+/** Consulta estructurada; se usa la primera forma presente. */
+export interface FindQuery {
+  /** Expresión de find where: "age >= 18 and (city = 'Lima' or city = 'Bogotá')". */
+  where?: string;
+  /** Consultas simples combinadas con AND: ["age>18", "city:Lima"]. */
+  queries?: string[];
+  /** Igualdad exacta sobre un campo. */
+  field?: string;
+  value?: FieldValue;
+  /** Restringe la búsqueda a estas colecciones. */
+  collections?: string[];
+}
+
+export type ExportFormat = 'json' | 'ndjson' | 'csv';
+
+export interface ExportOptions {
+  format?: ExportFormat;
+  collection?: string;
+  document?: string;
+}
+
+export interface ClientOptions {
+  /** Timeout por petición en milisegundos (30000 por defecto). */
+  timeout?: number;
+  /** fetch alternativo (por defecto globalThis.fetch). */
+  fetch?: typeof fetch;
+}
+
+export class MachDBError extends Error {
+  constructor(message: string, status?: number);
+  /** Código HTTP de la respuesta, si la hubo. */
+  status?: number;
+}
+export class NotFoundError extends MachDBError {}
+export class AlreadyExistsError extends MachDBError {}
+export class BadRequestError extends MachDBError {}
+
+export class Client {
+  constructor(url?: string, options?: ClientOptions);
+  readonly url: string;
+  /** Timeout por petición en milisegundos. */
+  readonly timeout: number;
+  databases(): Promise<string[]>;
+  createDatabase(name: string): Promise<Database>;
+  dropDatabase(name: string): Promise<void>;
+  db(name: string): Database;
+}
+
+export class Database {
+  readonly client: Client;
+  readonly name: string;
+  collections(): Promise<string[]>;
+  createCollection(name: string): Promise<Collection>;
+  dropCollection(name: string): Promise<void>;
+  collection(name: string): Collection;
+  find(query: string | FindQuery, collections?: string[]): Promise<QueryResult>;
+  export(options?: ExportOptions): Promise<string>;
+}
+
+export class Collection {
+  readonly database: Database;
+  readonly name: string;
+  documents(): Promise<string[]>;
+  createDocument(name: string): Promise<Document>;
+  dropDocument(name: string): Promise<void>;
+  document(name: string): Document;
+  find(query: string | FindQuery): Promise<QueryResult>;
+  export(options?: Omit<ExportOptions, 'collection'>): Promise<string>;
+}
+
+export class Document {
+  readonly collection: Collection;
+  readonly name: string;
+  insert(object: Fields): Promise<number>;
+  insert(objects: Fields[]): Promise<number[]>;
+  objects(): Promise<MachObject[]>;
+  export(options?: Pick<ExportOptions, 'format'>): Promise<string>;
+}
+
+export function connect(url?: string, options?: ClientOptions): Client;
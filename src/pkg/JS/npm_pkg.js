@@ -0,0 +1,228 @@
+'use strict';
+
+// Cliente JavaScript de machDB. Habla con un servidor db_server por HTTP/JSON
+// usando fetch (Node 18+ o navegador). Todas las operaciones devuelven Promises:
+//
+//   const { connect } = require('machdb');
+//   const client = connect('http://127.0.0.1:7070');
+//   const db = client.db('ventas');
+//   const id = await db.collection('users').document('clientes').insert({ name: 'Luis', age: 30 });
+//   const objs = await db.collection('users').find("age >= 18 and city = 'Lima'");
+
+class MachDBError extends Error {
+  constructor(message, status) {
+    super(message);
+    this.name = 'MachDBError';
+    this.status = status;
+  }
+}
+
+class NotFoundError extends MachDBError {
+  constructor(message, status) {
+    super(message, status);
+    this.name = 'NotFoundError';
+  }
+}
+
+class AlreadyExistsError extends MachDBError {
+  constructor(message, status) {
+    super(message, status);
+    this.name = 'AlreadyExistsError';
+  }
+}
+
+class BadRequestError extends MachDBError {
+  constructor(message, status) {
+    super(message, status);
+    this.name = 'BadRequestError';
+  }
+}
+
+const ERRORS_BY_STATUS = { 400: BadRequestError, 404: NotFoundError, 409: AlreadyExistsError };
+
+const EXPORT_FORMATS = ['json', 'ndjson', 'csv'];
+
+function path(...parts) {
+  return '/' + parts.map(encodeURIComponent).join('/');
+}
+
+class Client {
+  constructor(url = 'http://127.0.0.1:7070', options = {}) {
+    this.url = url.replace(/\/+$/, '');
+    this.timeout = options.timeout ?? 30000;
+    this.fetch = options.fetch ?? globalThis.fetch;
+    if (typeof this.fetch !== 'function') {
+      throw new MachDBError('fetch is not available; use Node 18+ or pass options.fetch');
+    }
+  }
+
+  async request(method, p, { body, query, raw = false } = {}) {
+    let url = this.url + p;
+    if (query) {
+      const params = new URLSearchParams();
+      for (const [k, v] of Object.entries(query)) {
+        if (v) params.set(k, v);
+      }
+      const qs = params.toString();
+      if (qs) url += '?' + qs;
+    }
+
+    const init = { method, signal: AbortSignal.timeout(this.timeout) };
+    if (body !== undefined) {
+      init.body = JSON.stringify(body);
+      init.headers = { 'Content-Type': 'application/json' };
+    }
+
+    const res = await this.fetch(url, init);
+    const text = await res.text();
+    if (!res.ok) {
+      let message;
+      try {
+        message = JSON.parse(text).error;
+      } catch (_) {
+        // cuerpo que no es JSON: se usa el status
+      }
+      const Err = ERRORS_BY_STATUS[res.status] ?? MachDBError;
+      throw new Err(message || `${method} ${p}: ${res.status} ${res.statusText}`, res.status);
+    }
+    if (raw) return text;
+    return text ? JSON.parse(text) : null;
+  }
+
+  async databases() {
+    return (await this.request('GET', path('databases'))).databases;
+  }
+
+  async createDatabase(name) {
+    await this.request('POST', path('databases'), { body: { name } });
+    return this.db(name);
+  }
+
+  async dropDatabase(name) {
+    await this.request('DELETE', path('databases', name));
+  }
+
+  db(name) {
+    return new Database(this, name);
+  }
+}
+
+class Database {
+  constructor(client, name) {
+    this.client = client;
+    this.name = name;
+  }
+
+  async collections() {
+    return (await this.client.request('GET', path('databases', this.name, 'collections'))).collections;
+  }
+
+  async createCollection(name) {
+    await this.client.request('POST', path('databases', this.name, 'collections'), { body: { name } });
+    return this.collection(name);
+  }
+
+  async dropCollection(name) {
+    await this.client.request('DELETE', path('databases', this.name, 'collections', name));
+  }
+
+  collection(name) {
+    return new Collection(this, name);
+  }
+
+  // find acepta la sintaxis de find where ("age >= 18 and city = 'Lima'") o
+  // { where, queries, field, value, collections } como el endpoint /find.
+  // Sin coincidencias resuelve a [].
+  async find(query, collections = []) {
+    const body = typeof query === 'string' ? { where: query } : { ...query };
+    if (collections.length && !body.collections) body.collections = collections;
+    const res = await this.client.request('POST', path('databases', this.name, 'find'), { body });
+    return res.objects;
+  }
+
+  // export devuelve el contenido como string en json (por defecto), ndjson o csv.
+  async export({ format = 'json', collection, document } = {}) {
+    if (!EXPORT_FORMATS.includes(format)) {
+      throw new MachDBError(`unknown export format ${format} (use json, ndjson or csv)`);
+    }
+    return this.client.request('GET', path('databases', this.name, 'export'), {
+      query: { format, collection, document },
+      raw: true,
+    });
+  }
+}
+
+class Collection {
+  constructor(database, name) {
+    this.database = database;
+    this.name = name;
+  }
+
+  path(...parts) {
+    return path('databases', this.database.name, 'collections', this.name, ...parts);
+  }
+
+  async documents() {
+    return (await this.database.client.request('GET', this.path('documents'))).documents;
+  }
+
+  async createDocument(name) {
+    await this.database.client.request('POST', this.path('documents'), { body: { name } });
+    return this.document(name);
+  }
+
+  async dropDocument(name) {
+    await this.database.client.request('DELETE', this.path('documents', name));
+  }
+
+  document(name) {
+    return new Document(this, name);
+  }
+
+  async find(query) {
+    return this.database.find(query, [this.name]);
+  }
+
+  async export(options = {}) {
+    return this.database.export({ ...options, collection: this.name });
+  }
+}
+
+class Document {
+  constructor(collection, name) {
+    this.collection = collection;
+    this.name = name;
+  }
+
+  // insert guarda un objeto (resuelve a su ID) o un array (resuelve a los IDs).
+  async insert(objects) {
+    const res = await this.collection.database.client.request('POST', this.collection.path('documents', this.name, 'objects'), {
+      body: objects,
+    });
+    return Array.isArray(objects) ? res.ids : res.ids[0];
+  }
+
+  async objects() {
+    return (await this.collection.database.client.request('GET', this.collection.path('documents', this.name, 'objects'))).objects;
+  }
+
+  async export(options = {}) {
+    return this.collection.database.export({ ...options, collection: this.collection.name, document: this.name });
+  }
+}
+
+function connect(url, options) {
+  return new Client(url, options);
+}
+
+module.exports = {
+  connect,
+  Client,
+  Database,
+  Collection,
+  Document,
+  MachDBError,
+  NotFoundError,
+  AlreadyExistsError,
+  BadRequestError,
+};
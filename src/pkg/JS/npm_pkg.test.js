@@ -0,0 +1,147 @@
+'use strict';
+
+// Prueba de humo contra un db_server real: node --test
+//
+// Usa el binario de $MACHDB_SERVER o lo compila con go build desde la raíz del
+// repositorio. También comprueba que npm_pkg.d.ts declare lo que exporta
+// npm_pkg.js.
+
+const test = require('node:test');
+const assert = require('node:assert/strict');
+const { spawn, execFileSync } = require('node:child_process');
+const fs = require('node:fs');
+const net = require('node:net');
+const os = require('node:os');
+const path = require('node:path');
+
+const machdb = require('./npm_pkg.js');
+const { connect, NotFoundError, AlreadyExistsError, BadRequestError, MachDBError } = machdb;
+
+const repoRoot = path.resolve(__dirname, '..', '..', '..');
+
+function serverBinary(tmp) {
+  if (process.env.MACHDB_SERVER) return process.env.MACHDB_SERVER;
+  const out = path.join(tmp, 'db_server');
+  execFileSync('go', ['build', '-o', out, './src/console/db_server'], { cwd: repoRoot, stdio: 'inherit' });
+  return out;
+}
+
+function freePort() {
+  return new Promise((resolve, reject) => {
+    const srv = net.createServer();
+    srv.on('error', reject);
+    srv.listen(0, '127.0.0.1', () => {
+      const { port } = srv.address();
+      srv.close(() => resolve(port));
+    });
+  });
+}
+
+// startServer arranca db_server con una base temporal y espera a que responda.
+async function startServer(t) {
+  const tmp = fs.mkdtempSync(path.join(os.tmpdir(), 'machdb-'));
+  const port = await freePort();
+  const proc = spawn(serverBinary(tmp), ['-path', path.join(tmp, 'data'), '-addr', `127.0.0.1:${port}`], {
+    stdio: 'ignore',
+  });
+  t.after(() => {
+    proc.kill();
+    fs.rmSync(tmp, { recursive: true, force: true });
+  });
+
+  const client = connect(`http://127.0.0.1:${port}/`, { timeout: 5000 });
+  const deadline = Date.now() + 10000;
+  for (;;) {
+    try {
+      await client.databases();
+      return client;
+    } catch (err) {
+      if (proc.exitCode !== null || Date.now() > deadline) throw err;
+      await new Promise((r) => setTimeout(r, 50));
+    }
+  }
+}
+
+test('client against db_server', async (t) => {
+  const client = await startServer(t);
+
+  const db = await client.createDatabase('ventas');
+  const users = await db.createCollection('users');
+  await db.createCollection('tmp');
+  const doc = await users.createDocument('clientes');
+
+  assert.equal(await doc.insert({ name: 'Luis', age: 30, city: 'Lima' }), 0);
+  assert.deepEqual(await doc.insert([{ name: 'Ana', age: 17, city: 'Lima' }, { name: 'Eva', age: 41.5 }]), [1, 2]);
+
+  const objs = await doc.objects();
+  assert.deepEqual(objs[2], { id: 2, fields: { name: 'Eva', age: 41.5 } });
+  assert.equal(objs.length, 3);
+
+  const found = await users.find("age >= 18 and city = 'Lima'");
+  assert.deepEqual(found.map((o) => o.fields.name), ['Luis']);
+  assert.deepEqual(await db.find({ queries: ['age<18'] }), [{ id: 1, fields: { name: 'Ana', age: 17, city: 'Lima' } }]);
+  assert.deepEqual(await db.find({ field: 'name', value: 'Eva' }, ['tmp']), []);
+  assert.deepEqual(await users.find("city = 'Bogotá'"), []);
+
+  assert.deepEqual(await client.databases(), ['ventas']);
+  assert.deepEqual(await db.collections(), ['tmp', 'users']);
+  assert.deepEqual(await users.documents(), ['clientes']);
+
+  const ndjson = (await doc.export({ format: 'ndjson' })).trim().split('\n');
+  assert.deepEqual(JSON.parse(ndjson[0]), {
+    collection: 'users',
+    document: 'clientes',
+    id: 0,
+    fields: { name: 'Luis', age: 30, city: 'Lima' },
+  });
+  const csv = await users.export({ format: 'csv' });
+  assert.equal(csv.split('\n')[0], '_collection,_document,_oid,age,city,name');
+  assert.equal(JSON.parse(await db.export()).database, 'ventas');
+
+  await db.dropCollection('tmp');
+  await users.dropDocument('clientes');
+  assert.deepEqual(await users.documents(), []);
+
+  const errors = [
+    [() => client.createDatabase('ventas'), AlreadyExistsError, 409],
+    [() => db.createCollection('users'), AlreadyExistsError, 409],
+    [() => client.dropDatabase('nope'), NotFoundError, 404],
+    [() => db.dropCollection('nope'), NotFoundError, 404],
+    [() => users.document('nope').insert({ a: 1 }), NotFoundError, 404],
+    [() => db.find('age >='), BadRequestError, 400],
+    [() => users.document('nope').insert([]), BadRequestError, 400],
+  ];
+  for (const [call, Err, status] of errors) {
+    await assert.rejects(call, (err) => err instanceof Err && err instanceof MachDBError && err.status === status);
+  }
+  await assert.rejects(db.export({ format: 'xml' }), MachDBError);
+
+  await client.dropDatabase('ventas');
+  assert.deepEqual(await client.databases(), []);
+});
+
+// Cada clase y función de npm_pkg.d.ts existe en npm_pkg.js con sus métodos y
+// propiedades, y todo lo que exporta npm_pkg.js está declarado.
+test('type definitions match the implementation', () => {
+  const dts = fs.readFileSync(path.join(__dirname, 'npm_pkg.d.ts'), 'utf8');
+  const declared = new Set();
+  for (const m of dts.matchAll(/^export (?:class|function) (\w+)/gm)) declared.add(m[1]);
+  assert.deepEqual([...declared].sort(), Object.keys(machdb).sort());
+
+  const client = new machdb.Client('http://127.0.0.1:1');
+  const db = client.db('d');
+  const col = db.collection('c');
+  const instances = { Client: client, Database: db, Collection: col, Document: col.document('x'), MachDBError: new MachDBError('m', 1) };
+  for (const m of dts.matchAll(/^export class (\w+)[^{]*\{([^}]*)\}/gm)) {
+    const [, name, body] = m;
+    const obj = instances[name];
+    if (!obj) continue;
+    for (const [, method] of body.matchAll(/^ {2}(\w+)\(/gm)) {
+      if (method === 'constructor') continue;
+      assert.equal(typeof obj[method], 'function', `${name}.${method}`);
+    }
+    for (const [, prop] of body.matchAll(/^ {2}(?:readonly )?(\w+)\??:/gm)) {
+      assert.ok(prop in obj, `${name}.${prop}`);
+    }
+  }
+});
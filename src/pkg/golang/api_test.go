@@ -0,0 +1,175 @@
+package api
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"machDB/src/internal/engine"
+	"machDB/src/internal/server"
+)
+
+// clients devuelve un cliente de cada modo: embebido sobre un directorio
+// temporal y remoto contra un db_server en un httptest.Server.
+func clients(t *testing.T) map[string]*Client {
+	t.Helper()
+	local, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(server.New(engine.NewEngine()))
+	t.Cleanup(srv.Close)
+	rem, err := Connect(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]*Client{"embedded": local, "remote": rem}
+}
+
+// Los dos modos dan los mismos resultados y los mismos errores.
+func TestClient(t *testing.T) {
+	for mode, c := range clients(t) {
+		t.Run(mode, func(t *testing.T) {
+			defer c.Close()
+			if err := c.CreateDatabase("ventas"); err != nil {
+				t.Fatal(err)
+			}
+			shop := c.DB("ventas")
+			for _, col := range []string{"users", "tmp"} {
+				if err := shop.CreateCollection(col); err != nil {
+					t.Fatal(err)
+				}
+			}
+			users := shop.Collection("users")
+			if err := users.CreateDocument("clientes"); err != nil {
+				t.Fatal(err)
+			}
+			doc := users.Document("clientes")
+
+			id, err := doc.Insert(map[string]interface{}{"name": "Luis", "age": 30, "city": "Lima"})
+			if err != nil || id != 0 {
+				t.Fatalf("Insert = %d, %v; want 0", id, err)
+			}
+			ids, err := doc.InsertMany([]map[string]interface{}{
+				{"name": "Ana", "age": 17, "city": "Lima"},
+				{"name": "Eva", "age": 41.5, "city": "Quito"},
+			})
+			if err != nil || !reflect.DeepEqual(ids, []int{1, 2}) {
+				t.Fatalf("InsertMany = %v, %v; want [1 2]", ids, err)
+			}
+
+			objs, err := doc.Objects()
+			if err != nil || len(objs) != 3 {
+				t.Fatalf("Objects = %v, %v; want 3 objects", objs, err)
+			}
+			want := Object{ID: 2, Fields: map[string]interface{}{"name": "Eva", "age": 41.5, "city": "Quito"}}
+			if !reflect.DeepEqual(objs[2], want) {
+				t.Errorf("Objects()[2] = %v, want %v", objs[2], want)
+			}
+
+			found, err := shop.Find("age >= 18 and city = 'Lima'")
+			if err != nil || len(found) != 1 || found[0].Fields["name"] != "Luis" || found[0].Fields["age"] != 30 {
+				t.Errorf("DB.Find = %v, %v; want Luis with age 30 (int)", found, err)
+			}
+			found, err = users.Find("city = 'Bogotá'")
+			if err != nil || found == nil || len(found) != 0 {
+				t.Errorf("Collection.Find with no matches = %#v, %v; want an empty list", found, err)
+			}
+
+			names := func(list func() ([]string, error), want ...string) {
+				t.Helper()
+				got, err := list()
+				if err != nil || len(got) != len(want) || len(want) > 0 && !reflect.DeepEqual(got, want) {
+					t.Errorf("list = %v, %v; want %v", got, err, want)
+				}
+			}
+			names(c.Databases, "ventas")
+			names(shop.Collections, "tmp", "users")
+			names(users.Documents, "clientes")
+
+			if err := shop.DropCollection("tmp"); err != nil {
+				t.Fatal(err)
+			}
+			names(shop.Collections, "users")
+			if err := users.DropDocument("clientes"); err != nil {
+				t.Fatal(err)
+			}
+			names(users.Documents)
+
+			errs := []struct {
+				name string
+				err  error
+				want error
+			}{
+				{"database exists", c.CreateDatabase("ventas"), ErrAlreadyExists},
+				{"collection exists", shop.CreateCollection("users"), ErrAlreadyExists},
+				{"missing database", c.DropDatabase("nope"), ErrNotFound},
+				{"missing collection", shop.DropCollection("nope"), ErrNotFound},
+				{"missing document", users.DropDocument("nope"), ErrNotFound},
+				{"bad query", second(shop.Find("age >=")), ErrBadQuery},
+				{"insert into a missing document", second(users.Document("nope").Insert(map[string]interface{}{"a": 1})), ErrNotFound},
+			}
+			for _, tc := range errs {
+				if !errors.Is(tc.err, tc.want) {
+					t.Errorf("%s: err = %v, want %v", tc.name, tc.err, tc.want)
+				}
+			}
+			if _, err := doc.InsertMany(nil); err == nil {
+				t.Error("InsertMany(nil): want error")
+			}
+
+			if err := c.DropDatabase("ventas"); err != nil {
+				t.Fatal(err)
+			}
+			names(c.Databases)
+		})
+	}
+}
+
+// En modo embebido lo guardado sigue ahí al volver a abrir la ruta.
+func TestEmbeddedReopen(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateDatabase("ventas"); err != nil {
+		t.Fatal(err)
+	}
+	users := c.DB("ventas")
+	if err := users.CreateCollection("users"); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Collection("users").CreateDocument("clientes"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Collection("users").Document("clientes").Insert(map[string]interface{}{"name": "Luis"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	objs, err := c.DB("ventas").Collection("users").Document("clientes").Objects()
+	want := []Object{{ID: 0, Fields: map[string]interface{}{"name": "Luis"}}}
+	if err != nil || !reflect.DeepEqual(objs, want) {
+		t.Errorf("after reopening: %v, %v; want %v", objs, err, want)
+	}
+}
+
+func TestConnectBadURL(t *testing.T) {
+	for _, u := range []string{"ftp://host", "localhost:7070", "http://%zz"} {
+		if _, err := Connect(u); err == nil {
+			t.Errorf("Connect(%q): want error", u)
+		}
+	}
+}
+
+// second descarta el primer resultado de una llamada y se queda con el error.
+func second[T any](_ T, err error) error { return err }
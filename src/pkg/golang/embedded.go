@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"sort"
+
+	db "machDB/src/internal/db"
+	"machDB/src/internal/engine"
+	"machDB/src/internal/query"
+	"machDB/src/internal/storage"
+)
+
+// embedded ejecuta el engine en el mismo proceso, con el WAL y el snapshot de path.
+type embedded struct {
+	engine *engine.Engine
+	store  *storage.Storage
+}
+
+// Open carga (o crea) la base en path: un directorio o un archivo .machdb, igual
+// que db_cli -path. Cada cambio queda en el WAL; Close guarda el snapshot.
+func Open(path string) (*Client, error) {
+	e := engine.NewEngine()
+	s := storage.NewStorage(path)
+	if err := s.LoadFromDisk(e); err != nil {
+		return nil, err
+	}
+	return &Client{b: &embedded{engine: e, store: s}}, nil
+}
+
+func (b *embedded) close() error {
+	err := b.store.FlushToDisk(b.engine)
+	if cerr := b.store.Close(b.engine); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (b *embedded) listDatabases() ([]string, error) {
+	names := b.engine.ListDatabases()
+	sort.Strings(names)
+	return names, nil
+}
+
+func (b *embedded) createDatabase(name string) error {
+	return b.engine.CreateDatabase(name)
+}
+
+func (b *embedded) deleteDatabase(name string) error {
+	return b.engine.DeleteDatabase(name)
+}
+
+func (b *embedded) listCollections(dbName string) ([]string, error) {
+	names, err := b.engine.ListCollections(dbName)
+	sort.Strings(names)
+	return names, err
+}
+
+func (b *embedded) createCollection(dbName, colName string) error {
+	return b.engine.CreateCollection(dbName, colName)
+}
+
+func (b *embedded) deleteCollection(dbName, colName string) error {
+	return b.engine.DeleteCollection(dbName, colName)
+}
+
+func (b *embedded) listDocuments(dbName, colName string) ([]string, error) {
+	names, err := b.engine.ListDocuments(dbName, colName)
+	sort.Strings(names)
+	return names, err
+}
+
+func (b *embedded) createDocument(dbName, colName, docName string) error {
+	return b.engine.CreateDocument(dbName, colName, docName)
+}
+
+func (b *embedded) deleteDocument(dbName, colName, docName string) error {
+	return b.engine.DeleteDocument(dbName, colName, docName)
+}
+
+func (b *embedded) listObjects(dbName, colName, docName string) ([]Object, error) {
+	objs, err := b.engine.ListObjects(dbName, colName, docName)
+	if err != nil {
+		return nil, err
+	}
+	return toObjects(objs), nil
+}
+
+// insert copia cada mapa: el engine se queda con el que recibe y el llamador
+// podría seguir modificando el suyo.
+func (b *embedded) insert(dbName, colName, docName string, objs []map[string]interface{}) ([]int, error) {
+	copies := make([]map[string]interface{}, len(objs))
+	for n, fields := range objs {
+		copies[n] = db.NewObject(0, fields).Clone().Fields
+	}
+	return b.engine.InsertObjects(dbName, colName, docName, copies)
+}
+
+func (b *embedded) find(dbName, where string, collections []string) ([]Object, error) {
+	f, err := query.ParseWhere(where)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := b.engine.FindWhere(f, dbName, collections...)
+	if errors.Is(err, engine.ErrNoResults) {
+		return []Object{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toObjects(objs), nil
+}
+
+func toObjects(objs []*db.Object) []Object {
+	out := make([]Object, 0, len(objs))
+	for _, obj := range objs {
+		out = append(out, Object{ID: obj.ID, Fields: obj.Fields})
+	}
+	return out
+}
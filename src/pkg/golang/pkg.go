@@ -1 +1,179 @@
+// Package api es el cliente Go de machDB. El mismo API funciona con el engine
+// embebido en el proceso (Open) o contra un servidor db_server (Connect):
+//
+//	c, err := api.Open("/data/machdb")
+//	defer c.Close()
+//	users := c.DB("ventas").Collection("users")
+//	id, err := users.Document("clientes").Insert(map[string]interface{}{"name": "Luis", "age": 30})
+//	objs, err := users.Find("age >= 18 and city = 'Lima'")
+//
+// El paquete se importa como "machDB/src/pkg/golang". La ruta del módulo,
+// machDB, no es una URL, así que go get no puede descargarla: desde otro
+// módulo hay que requerirla y apuntarla con replace a una copia local del
+// repositorio en el go.mod de ese módulo (y después go mod tidy):
+//
+//	require machDB v0.0.0
+//
+//	replace machDB => ../machDB
 package api
+
+import (
+	"errors"
+
+	db "machDB/src/internal/db"
+	"machDB/src/internal/engine"
+)
+
+// Errores que devuelven ambos modos; se comparan con errors.Is.
+var (
+	ErrNotFound      = db.ErrNotFound
+	ErrAlreadyExists = db.ErrAlreadyExists
+	ErrBadQuery      = engine.ErrBadQuery
+)
+
+// Object es un objeto guardado: su ID dentro del documento y sus campos.
+type Object struct {
+	ID     int                    `json:"id"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+// backend es lo que cambia entre el modo embebido y el remoto.
+type backend interface {
+	listDatabases() ([]string, error)
+	createDatabase(name string) error
+	deleteDatabase(name string) error
+	listCollections(dbName string) ([]string, error)
+	createCollection(dbName, colName string) error
+	deleteCollection(dbName, colName string) error
+	listDocuments(dbName, colName string) ([]string, error)
+	createDocument(dbName, colName, docName string) error
+	deleteDocument(dbName, colName, docName string) error
+	listObjects(dbName, colName, docName string) ([]Object, error)
+	insert(dbName, colName, docName string, objs []map[string]interface{}) ([]int, error)
+	find(dbName, where string, collections []string) ([]Object, error)
+	close() error
+}
+
+// Client es una conexión a machDB, embebida o remota.
+type Client struct {
+	b backend
+}
+
+// Close libera el cliente. En modo embebido además guarda el estado en disco.
+func (c *Client) Close() error {
+	return c.b.close()
+}
+
+// Databases lista las bases de datos ordenadas por nombre.
+func (c *Client) Databases() ([]string, error) {
+	return c.b.listDatabases()
+}
+
+// CreateDatabase crea una base de datos; falla con ErrAlreadyExists si ya existe.
+func (c *Client) CreateDatabase(name string) error {
+	return c.b.createDatabase(name)
+}
+
+// DropDatabase borra una base de datos con todo su contenido.
+func (c *Client) DropDatabase(name string) error {
+	return c.b.deleteDatabase(name)
+}
+
+// DB devuelve un manejador de la base de datos name; no comprueba que exista.
+func (c *Client) DB(name string) *DB {
+	return &DB{c: c, name: name}
+}
+
+// DB es un manejador de una base de datos.
+type DB struct {
+	c    *Client
+	name string
+}
+
+func (d *DB) Name() string { return d.name }
+
+// Collections lista las colecciones ordenadas por nombre.
+func (d *DB) Collections() ([]string, error) {
+	return d.c.b.listCollections(d.name)
+}
+
+func (d *DB) CreateCollection(name string) error {
+	return d.c.b.createCollection(d.name, name)
+}
+
+func (d *DB) DropCollection(name string) error {
+	return d.c.b.deleteCollection(d.name, name)
+}
+
+// Collection devuelve un manejador de la colección name; no comprueba que exista.
+func (d *DB) Collection(name string) *Collection {
+	return &Collection{db: d, name: name}
+}
+
+// Find busca en toda la base de datos con la sintaxis de find where
+// ("age >= 18 and (city = 'Lima' or city = 'Bogotá')"). Sin coincidencias
+// devuelve una lista vacía.
+func (d *DB) Find(where string) ([]Object, error) {
+	return d.c.b.find(d.name, where, nil)
+}
+
+// Collection es un manejador de una colección.
+type Collection struct {
+	db   *DB
+	name string
+}
+
+func (c *Collection) Name() string { return c.name }
+
+// Documents lista los documentos ordenados por nombre.
+func (c *Collection) Documents() ([]string, error) {
+	return c.db.c.b.listDocuments(c.db.name, c.name)
+}
+
+func (c *Collection) CreateDocument(name string) error {
+	return c.db.c.b.createDocument(c.db.name, c.name, name)
+}
+
+func (c *Collection) DropDocument(name string) error {
+	return c.db.c.b.deleteDocument(c.db.name, c.name, name)
+}
+
+// Document devuelve un manejador del documento name; no comprueba que exista.
+func (c *Collection) Document(name string) *Document {
+	return &Document{col: c, name: name}
+}
+
+// Find es como DB.Find pero solo dentro de esta colección.
+func (c *Collection) Find(where string) ([]Object, error) {
+	return c.db.c.b.find(c.db.name, where, []string{c.name})
+}
+
+// Document es un manejador de un documento.
+type Document struct {
+	col  *Collection
+	name string
+}
+
+func (d *Document) Name() string { return d.name }
+
+// Insert guarda fields como un objeto nuevo y devuelve su ID.
+func (d *Document) Insert(fields map[string]interface{}) (int, error) {
+	ids, err := d.InsertMany([]map[string]interface{}{fields})
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// InsertMany guarda varios objetos y devuelve sus IDs en el mismo orden.
+func (d *Document) InsertMany(objs []map[string]interface{}) ([]int, error) {
+	if len(objs) == 0 {
+		return nil, errors.New("no objects to insert")
+	}
+	return d.col.db.c.b.insert(d.col.db.name, d.col.name, d.name, objs)
+}
+
+// Objects devuelve todos los objetos del documento en orden de inserción.
+func (d *Document) Objects() ([]Object, error) {
+	return d.col.db.c.b.listObjects(d.col.db.name, d.col.name, d.name)
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	db "machDB/src/internal/db"
+)
+
+// remote habla con un db_server por HTTP (ver internal/server para las rutas).
+type remote struct {
+	base string
+	http *http.Client
+}
+
+// Connect devuelve un cliente contra el servidor en baseURL ("http://host:7070").
+// No abre ninguna conexión hasta la primera llamada.
+func Connect(baseURL string) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+	return &Client{b: &remote{
+		base: strings.TrimRight(baseURL, "/"),
+		http: &http.Client{Timeout: 30 * time.Second},
+	}}, nil
+}
+
+// remoteError conserva el mensaje del servidor y envuelve el error base según el
+// código HTTP, para que errors.Is funcione igual que en modo embebido.
+type remoteError struct {
+	msg  string
+	kind error
+}
+
+func (e *remoteError) Error() string { return e.msg }
+func (e *remoteError) Unwrap() error { return e.kind }
+
+func (b *remote) close() error {
+	b.http.CloseIdleConnections()
+	return nil
+}
+
+// path arma la ruta escapando cada segmento.
+func path(parts ...string) string {
+	var sb strings.Builder
+	for _, p := range parts {
+		sb.WriteByte('/')
+		sb.WriteString(url.PathEscape(p))
+	}
+	return sb.String()
+}
+
+// do envía body como JSON y decodifica la respuesta en out (si no es nil).
+func (b *remote) do(method, p string, body, out interface{}) error {
+	var rd io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, b.base+p, rd)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &e) != nil || e.Error == "" {
+			e.Error = fmt.Sprintf("%s %s: %s", method, p, resp.Status)
+		}
+		rerr := &remoteError{msg: e.Error}
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			rerr.kind = ErrNotFound
+		case http.StatusConflict:
+			rerr.kind = ErrAlreadyExists
+		case http.StatusBadRequest:
+			rerr.kind = ErrBadQuery
+		}
+		return rerr
+	}
+	if out == nil {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(out)
+}
+
+func (b *remote) list(key string, parts ...string) ([]string, error) {
+	var out map[string][]string
+	if err := b.do(http.MethodGet, path(parts...), nil, &out); err != nil {
+		return nil, err
+	}
+	return out[key], nil
+}
+
+func (b *remote) listDatabases() ([]string, error) {
+	return b.list("databases", "databases")
+}
+
+func (b *remote) createDatabase(name string) error {
+	return b.do(http.MethodPost, path("databases"), map[string]string{"name": name}, nil)
+}
+
+func (b *remote) deleteDatabase(name string) error {
+	return b.do(http.MethodDelete, path("databases", name), nil, nil)
+}
+
+func (b *remote) listCollections(dbName string) ([]string, error) {
+	return b.list("collections", "databases", dbName, "collections")
+}
+
+func (b *remote) createCollection(dbName, colName string) error {
+	return b.do(http.MethodPost, path("databases", dbName, "collections"), map[string]string{"name": colName}, nil)
+}
+
+func (b *remote) deleteCollection(dbName, colName string) error {
+	return b.do(http.MethodDelete, path("databases", dbName, "collections", colName), nil, nil)
+}
+
+func (b *remote) listDocuments(dbName, colName string) ([]string, error) {
+	return b.list("documents", "databases", dbName, "collections", colName, "documents")
+}
+
+func (b *remote) createDocument(dbName, colName, docName string) error {
+	return b.do(http.MethodPost, path("databases", dbName, "collections", colName, "documents"), map[string]string{"name": docName}, nil)
+}
+
+func (b *remote) deleteDocument(dbName, colName, docName string) error {
+	return b.do(http.MethodDelete, path("databases", dbName, "collections", colName, "documents", docName), nil, nil)
+}
+
+func (b *remote) listObjects(dbName, colName, docName string) ([]Object, error) {
+	var out struct {
+		Objects []Object `json:"objects"`
+	}
+	p := path("databases", dbName, "collections", colName, "documents", docName, "objects")
+	if err := b.do(http.MethodGet, p, nil, &out); err != nil {
+		return nil, err
+	}
+	return normalizeObjects(out.Objects), nil
+}
+
+func (b *remote) insert(dbName, colName, docName string, objs []map[string]interface{}) ([]int, error) {
+	var out struct {
+		IDs []int `json:"ids"`
+	}
+	p := path("databases", dbName, "collections", colName, "documents", docName, "objects")
+	if err := b.do(http.MethodPost, p, objs, &out); err != nil {
+		return nil, err
+	}
+	return out.IDs, nil
+}
+
+func (b *remote) find(dbName, where string, collections []string) ([]Object, error) {
+	var out struct {
+		Objects []Object `json:"objects"`
+	}
+	body := map[string]interface{}{"where": where, "collections": collections}
+	if err := b.do(http.MethodPost, path("databases", dbName, "find"), body, &out); err != nil {
+		return nil, err
+	}
+	return normalizeObjects(out.Objects), nil
+}
+
+// normalizeObjects deja los números como int o float64, igual que en modo embebido.
+func normalizeObjects(objs []Object) []Object {
+	if objs == nil {
+		return []Object{}
+	}
+	for _, obj := range objs {
+		for k, v := range obj.Fields {
+			obj.Fields[k] = db.NormalizeNumber(v)
+		}
+	}
+	return objs
+}
@@ -0,0 +1,382 @@
+"""Cliente Python de machDB.
+
+Habla con un servidor ``db_server`` por HTTP/JSON usando solo la librería
+estándar. Los objetos se devuelven como ``Object(id, fields)`` donde ``fields``
+es un ``dict`` normal::
+
+    from machdb import Client
+
+    c = Client("http://127.0.0.1:7070")
+    c.create_database("ventas")
+    c.select("ventas")
+    c.create_collection("users")
+    c.select("ventas", "users")
+    c.create_document("clientes")
+    c.insert("clientes", {"name": "Luis", "age": 30})
+    for obj in c.find("age >= 18 and city = 'Lima'"):
+        print(obj.id, obj.fields["name"])
+    c.export("users.csv")
+
+Para pruebas, ``LocalServer`` arranca un binario db_server en un puerto libre
+con una base temporal y lo para al salir del ``with``.
+"""
+
+import json
+import os
+import shutil
+import socket
+import subprocess
+import tempfile
+import time
+import urllib.error
+import urllib.parse
+import urllib.request
+from dataclasses import dataclass, field
+
+__all__ = [
+    "Client",
+    "Database",
+    "Collection",
+    "Document",
+    "Object",
+    "MachDBError",
+    "NotFoundError",
+    "AlreadyExistsError",
+    "BadRequestError",
+    "LocalServer",
+]
+
+EXPORT_FORMATS = {".json": "json", ".ndjson": "ndjson", ".jsonl": "ndjson", ".csv": "csv"}
+
+
+class MachDBError(Exception):
+    """Error devuelto por el servidor; ``status`` es el código HTTP."""
+
+    def __init__(self, message, status=None):
+        super().__init__(message)
+        self.message = message
+        self.status = status
+
+
+class NotFoundError(MachDBError):
+    """La base de datos, colección o documento no existe (404)."""
+
+
+class AlreadyExistsError(MachDBError):
+    """La base de datos, colección o documento ya existe (409)."""
+
+
+class BadRequestError(MachDBError):
+    """Petición o consulta inválida (400)."""
+
+
+_ERRORS_BY_STATUS = {404: NotFoundError, 409: AlreadyExistsError, 400: BadRequestError}
+
+
+@dataclass
+class Object:
+    """Un objeto de un documento: su ID y sus campos."""
+
+    id: int
+    fields: dict = field(default_factory=dict)
+
+    def __getitem__(self, key):
+        return self.fields[key]
+
+    def get(self, key, default=None):
+        return self.fields.get(key, default)
+
+    @classmethod
+    def from_json(cls, data):
+        return cls(id=data["id"], fields=data.get("fields") or {})
+
+
+def _path(*parts):
+    return "/" + "/".join(urllib.parse.quote(p, safe="") for p in parts)
+
+
+class Client:
+    """Conexión a un servidor machDB.
+
+    Igual que en db_cli, ``select`` fija la base de datos y la colección que usan
+    por defecto ``create_*``, ``insert``, ``find`` y ``export``.
+    """
+
+    def __init__(self, url="http://127.0.0.1:7070", timeout=30):
+        self.url = url.rstrip("/")
+        self.timeout = timeout
+        self.current_db = None
+        self.current_collection = None
+
+    # --- transporte ---
+
+    def _request(self, method, path, body=None, query=None, raw=False):
+        url = self.url + path
+        if query:
+            url += "?" + urllib.parse.urlencode({k: v for k, v in query.items() if v})
+        data = None
+        headers = {}
+        if body is not None:
+            data = json.dumps(body).encode("utf-8")
+            headers["Content-Type"] = "application/json"
+        req = urllib.request.Request(url, data=data, method=method, headers=headers)
+        try:
+            with urllib.request.urlopen(req, timeout=self.timeout) as resp:
+                payload = resp.read()
+        except urllib.error.HTTPError as e:
+            payload = e.read()
+            try:
+                message = json.loads(payload)["error"]
+            except (ValueError, KeyError, TypeError):
+                message = "%s %s: %s %s" % (method, path, e.code, e.reason)
+            raise _ERRORS_BY_STATUS.get(e.code, MachDBError)(message, e.code) from None
+        if raw:
+            return payload
+        if not payload:
+            return None
+        return json.loads(payload)
+
+    # --- selección ---
+
+    def select(self, db, collection=None):
+        """Selecciona la base de datos (y opcionalmente la colección) por defecto.
+
+        Comprueba que existan, como ``select db``/``select collection`` del CLI.
+        """
+        if db not in self.databases():
+            raise NotFoundError("database %s not found" % db, 404)
+        if collection is not None and collection not in self.db(db).collections():
+            raise NotFoundError("collection %s not found" % collection, 404)
+        self.current_db = db
+        self.current_collection = collection
+        return self.db(db)
+
+    def _db_name(self, db):
+        name = db or self.current_db
+        if not name:
+            raise MachDBError("no database selected")
+        return name
+
+    def _collection_name(self, collection):
+        name = collection or self.current_collection
+        if not name:
+            raise MachDBError("no collection selected")
+        return name
+
+    # --- bases de datos ---
+
+    def databases(self):
+        return self._request("GET", _path("databases"))["databases"]
+
+    def create_database(self, name):
+        self._request("POST", _path("databases"), {"name": name})
+        return self.db(name)
+
+    def drop_database(self, name):
+        self._request("DELETE", _path("databases", name))
+        if self.current_db == name:
+            self.current_db = self.current_collection = None
+
+    def db(self, name=None):
+        return Database(self, self._db_name(name))
+
+    # --- atajos sobre la selección actual ---
+
+    def create_collection(self, name, db=None):
+        return self.db(db).create_collection(name)
+
+    def create_document(self, name, collection=None, db=None):
+        return self.db(db).collection(self._collection_name(collection)).create_document(name)
+
+    def insert(self, document, objects, collection=None, db=None):
+        """Inserta un dict o una lista de dicts y devuelve el ID o la lista de IDs."""
+        doc = self.db(db).collection(self._collection_name(collection)).document(document)
+        return doc.insert(objects)
+
+    def find(self, where, collections=None, db=None):
+        """Busca con la sintaxis de ``find where``.
+
+        Sin ``collections`` usa la colección seleccionada o, si no hay, toda la
+        base de datos. Sin coincidencias devuelve una lista vacía.
+        """
+        if collections is None and self.current_collection and db is None:
+            collections = [self.current_collection]
+        return self.db(db).find(where, collections)
+
+    def export(self, path=None, format=None, collection=None, document=None, db=None):
+        """Exporta la base de datos, una colección o un documento.
+
+        Con ``path`` el formato sale de la extensión (.json, .ndjson/.jsonl, .csv)
+        y se escribe el archivo; sin ``path`` devuelve el contenido como ``str``.
+        """
+        if collection is None and document is not None:
+            collection = self.current_collection
+        return self.db(db).export(path, format, collection, document)
+
+
+class Database:
+    def __init__(self, client, name):
+        self.client = client
+        self.name = name
+
+    def __repr__(self):
+        return "Database(%r)" % self.name
+
+    def collections(self):
+        return self.client._request("GET", _path("databases", self.name, "collections"))["collections"]
+
+    def create_collection(self, name):
+        self.client._request("POST", _path("databases", self.name, "collections"), {"name": name})
+        return self.collection(name)
+
+    def drop_collection(self, name):
+        self.client._request("DELETE", _path("databases", self.name, "collections", name))
+
+    def collection(self, name):
+        return Collection(self, name)
+
+    def find(self, where, collections=None):
+        body = {"where": where, "collections": list(collections or [])}
+        res = self.client._request("POST", _path("databases", self.name, "find"), body)
+        return [Object.from_json(o) for o in res["objects"]]
+
+    def export(self, path=None, format=None, collection=None, document=None):
+        if format is None:
+            if path is None:
+                format = "json"
+            else:
+                ext = os.path.splitext(path)[1].lower()
+                if ext not in EXPORT_FORMATS:
+                    raise MachDBError("unknown export format for %s (use .json, .ndjson or .csv)" % path)
+                format = EXPORT_FORMATS[ext]
+        query = {"format": format, "collection": collection, "document": document}
+        data = self.client._request("GET", _path("databases", self.name, "export"), query=query, raw=True)
+        if path is None:
+            return data.decode("utf-8")
+        tmp = path + ".tmp"
+        with open(tmp, "wb") as f:
+            f.write(data)
+        os.replace(tmp, path)
+        return path
+
+
+class Collection:
+    def __init__(self, database, name):
+        self.database = database
+        self.name = name
+
+    def __repr__(self):
+        return "Collection(%r, %r)" % (self.database.name, self.name)
+
+    def _path(self, *parts):
+        return _path("databases", self.database.name, "collections", self.name, *parts)
+
+    def documents(self):
+        return self.database.client._request("GET", self._path("documents"))["documents"]
+
+    def create_document(self, name):
+        self.database.client._request("POST", self._path("documents"), {"name": name})
+        return self.document(name)
+
+    def drop_document(self, name):
+        self.database.client._request("DELETE", self._path("documents", name))
+
+    def document(self, name):
+        return Document(self, name)
+
+    def find(self, where):
+        return self.database.find(where, [self.name])
+
+    def export(self, path=None, format=None):
+        return self.database.export(path, format, collection=self.name)
+
+
+class Document:
+    def __init__(self, collection, name):
+        self.collection = collection
+        self.name = name
+
+    def __repr__(self):
+        return "Document(%r, %r, %r)" % (self.collection.database.name, self.collection.name, self.name)
+
+    def _path(self):
+        return self.collection._path("documents", self.name, "objects")
+
+    def insert(self, objects):
+        """Inserta un dict (devuelve su ID) o una lista de dicts (devuelve los IDs)."""
+        single = isinstance(objects, dict)
+        res = self.collection.database.client._request("POST", self._path(), objects)
+        return res["ids"][0] if single else res["ids"]
+
+    def objects(self):
+        res = self.collection.database.client._request("GET", self._path())
+        return [Object.from_json(o) for o in res["objects"]]
+
+    def export(self, path=None, format=None):
+        return self.collection.database.export(path, format, self.collection.name, self.name)
+
+
+class LocalServer:
+    """Arranca ``db_server`` en un puerto libre para pruebas::
+
+        with LocalServer("./db_server") as client:
+            client.create_database("test")
+
+    ``binary`` por defecto es $MACHDB_SERVER o ``db_server`` en el PATH. Sin
+    ``path`` se usa un directorio temporal que se borra al salir.
+    """
+
+    def __init__(self, binary=None, path=None, startup_timeout=10):
+        self.binary = binary or os.environ.get("MACHDB_SERVER") or shutil.which("db_server")
+        if not self.binary:
+            raise MachDBError("db_server binary not found (set MACHDB_SERVER)")
+        self._tmpdir = None
+        if path is None:
+            self._tmpdir = tempfile.mkdtemp(prefix="machdb-")
+            path = os.path.join(self._tmpdir, "data")
+        self.path = path
+        self.startup_timeout = startup_timeout
+        self.proc = None
+        self.url = None
+
+    def start(self):
+        with socket.socket() as s:
+            s.bind(("127.0.0.1", 0))
+            port = s.getsockname()[1]
+        self.url = "http://127.0.0.1:%d" % port
+        self.proc = subprocess.Popen(
+            [self.binary, "-path", self.path, "-addr", "127.0.0.1:%d" % port],
+            stdout=subprocess.DEVNULL,
+            stderr=subprocess.DEVNULL,
+        )
+        client = Client(self.url)
+        deadline = time.monotonic() + self.startup_timeout
+        while True:
+            try:
+                client.databases()
+                return client
+            except (urllib.error.URLError, ConnectionError):
+                if self.proc.poll() is not None or time.monotonic() > deadline:
+                    self.stop()
+                    raise MachDBError("db_server did not start on %s" % self.url)
+                time.sleep(0.05)
+
+    def stop(self):
+        if self.proc is not None and self.proc.poll() is None:
+            self.proc.terminate()
+            try:
+                self.proc.wait(timeout=10)
+            except subprocess.TimeoutExpired:
+                self.proc.kill()
+                self.proc.wait()
+        self.proc = None
+        if self._tmpdir is not None:
+            shutil.rmtree(self._tmpdir, ignore_errors=True)
+            self._tmpdir = None
+
+    def __enter__(self):
+        return self.start()
+
+    def __exit__(self, *exc):
+        self.stop()
+        return False
@@ -0,0 +1,149 @@
+"""Pruebas del cliente contra un db_server real (``pytest``).
+
+Usa el binario de $MACHDB_SERVER o ``db_server`` en el PATH; si no hay ninguno
+lo compila con ``go build`` desde la raíz del repositorio.
+"""
+
+import json
+import os
+import pathlib
+import shutil
+import subprocess
+import urllib.error
+
+import pytest
+
+from machdb import (
+    AlreadyExistsError,
+    BadRequestError,
+    Client,
+    LocalServer,
+    MachDBError,
+    NotFoundError,
+    Object,
+)
+
+REPO_ROOT = pathlib.Path(__file__).resolve().parents[3]
+
+
+@pytest.fixture(scope="module")
+def binary(tmp_path_factory):
+    found = os.environ.get("MACHDB_SERVER") or shutil.which("db_server")
+    if found:
+        return found
+    go = shutil.which("go")
+    if not go:
+        pytest.skip("no db_server binary and no go toolchain to build it")
+    out = str(tmp_path_factory.mktemp("bin") / "db_server")
+    subprocess.run([go, "build", "-o", out, "./src/console/db_server"], cwd=REPO_ROOT, check=True)
+    return out
+
+
+@pytest.fixture(scope="module")
+def server(binary):
+    with LocalServer(binary) as client:
+        yield client
+
+
+@pytest.fixture
+def client(server):
+    """Un cliente nuevo con la base de datos ``ventas`` creada y seleccionada."""
+    c = Client(server.url)
+    c.create_database("ventas")
+    c.select("ventas")
+    yield c
+    c.drop_database("ventas")
+
+
+def test_crud_and_find(client):
+    client.create_collection("users")
+    client.create_collection("tmp")
+    client.select("ventas", "users")
+    doc = client.create_document("clientes")
+
+    assert client.insert("clientes", {"name": "Luis", "age": 30, "city": "Lima"}) == 0
+    assert doc.insert([{"name": "Ana", "age": 17, "city": "Lima"}, {"name": "Eva", "age": 41.5}]) == [1, 2]
+
+    objs = doc.objects()
+    assert [o.id for o in objs] == [0, 1, 2]
+    assert objs[2] == Object(2, {"name": "Eva", "age": 41.5})
+    assert objs[0]["name"] == "Luis" and objs[0].get("missing", "-") == "-"
+
+    found = client.find("age >= 18 and city = 'Lima'")
+    assert [(o.id, o["name"]) for o in found] == [(0, "Luis")]
+    assert client.find("city = 'Bogotá'") == []
+    assert client.db().find("age < 18", ["users"])[0]["name"] == "Ana"
+
+    ventas = client.db()
+    assert client.databases() == ["ventas"]
+    assert ventas.collections() == ["tmp", "users"]
+    assert ventas.collection("users").documents() == ["clientes"]
+    ventas.drop_collection("tmp")
+    assert ventas.collections() == ["users"]
+    ventas.collection("users").drop_document("clientes")
+    assert ventas.collection("users").documents() == []
+
+
+@pytest.mark.parametrize(
+    "call, error, status",
+    [
+        (lambda c: c.create_database("ventas"), AlreadyExistsError, 409),
+        (lambda c: c.create_collection("users"), AlreadyExistsError, 409),
+        (lambda c: c.drop_database("nope"), NotFoundError, 404),
+        (lambda c: c.db().drop_collection("nope"), NotFoundError, 404),
+        (lambda c: c.select("nope"), NotFoundError, 404),
+        (lambda c: c.select("ventas", "nope"), NotFoundError, 404),
+        (lambda c: c.insert("nope", {"a": 1}, collection="users"), NotFoundError, 404),
+        (lambda c: c.find("age >="), BadRequestError, 400),
+        (lambda c: c.db().collection("users").document("d").insert([]), BadRequestError, 400),
+        (lambda c: c.export(format="xml"), BadRequestError, 400),
+    ],
+)
+def test_errors(client, call, error, status):
+    client.create_collection("users")
+    with pytest.raises(error) as info:
+        call(client)
+    assert info.value.status == status
+    assert isinstance(info.value, MachDBError) and info.value.message
+
+
+def test_selection_required(server):
+    c = Client(server.url)
+    with pytest.raises(MachDBError, match="no database selected"):
+        c.find("a = 1")
+    c.current_db = "ventas"
+    with pytest.raises(MachDBError, match="no collection selected"):
+        c.create_document("d")
+
+
+def test_export(client, tmp_path):
+    users = client.create_collection("users")
+    users.create_document("clientes").insert([{"name": "Luis"}, {"name": "Ana"}])
+
+    exp = json.loads(client.export())
+    assert exp["database"] == "ventas"
+    assert len(exp["collections"]["users"]["documents"]["clientes"]) == 2
+
+    lines = users.export(format="ndjson").splitlines()
+    assert json.loads(lines[1]) == {"collection": "users", "document": "clientes", "id": 1, "fields": {"name": "Ana"}}
+
+    path = str(tmp_path / "users.csv")
+    assert users.document("clientes").export(path) == path
+    with open(path) as f:
+        assert f.read().splitlines() == ["_collection,_document,_oid,name", "users,clientes,0,Luis", "users,clientes,1,Ana"]
+    assert not os.path.exists(path + ".tmp")
+
+    with pytest.raises(MachDBError, match="unknown export format"):
+        client.export(str(tmp_path / "users.xml"))
+
+
+def test_local_server_stops(binary):
+    server = LocalServer(binary)
+    with server as c:
+        url = c.url
+        c.create_database("x")
+        tmpdir = server._tmpdir
+    assert server.proc is None
+    assert not os.path.exists(tmpdir)
+    with pytest.raises(urllib.error.URLError):
+        Client(url, timeout=1).databases()